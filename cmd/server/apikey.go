@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/repository"
+	"subscription_service/pkg/service"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiKeyUsage перечисляет области доступа из auth.Scopes, чтобы справка не расходилась с проверкой при создании ключа
+var apiKeyUsage = `Управление ключами API внутренних сервисов:
+
+  subscription apikey create -name <имя> -scopes <области через запятую>
+  subscription apikey list
+  subscription apikey revoke <id>
+
+Области доступа: ` + strings.Join(auth.Scopes, ", ") + "\n"
+
+// runAPIKeyCommand выполняет подкоманду apikey и возвращает код завершения процесса
+func runAPIKeyCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, apiKeyUsage)
+		return 2
+	}
+
+	// Логи подкоманды выводятся в stderr, чтобы не смешиваться с её результатом
+	logger.SetOutput(stderr)
+
+	pool, connStr, err := connectToDB()
+	if err != nil {
+		fmt.Fprintln(stderr, "Ошибка подключения к БД:", err)
+		return 1
+	}
+	defer pool.Close()
+
+	if err := repository.MigrateDB(connStr); err != nil && err.Error() != "no change" {
+		fmt.Fprintln(stderr, "Ошибка миграции БД:", err)
+		return 1
+	}
+
+	keys := service.NewAPIKeyService(repository.NewAPIKeyRepo(pool, logger), logger)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		flags.SetOutput(stderr)
+		name := flags.String("name", "", "имя сервиса, которому выдаётся ключ")
+		scopes := flags.String("scopes", "", "области доступа через запятую")
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+
+		rawKey, key, err := keys.Create(ctx, *name, splitScopes(*scopes))
+		if err != nil {
+			fmt.Fprintln(stderr, "Ошибка создания ключа:", err)
+			return 1
+		}
+
+		fmt.Fprintf(stdout, "id:     %s\nname:   %s\nscopes: %s\nkey:    %s\n", key.ID, key.Name, strings.Join(key.Scopes, ","), rawKey)
+		fmt.Fprintln(stderr, "Сохраните ключ: повторно получить его нельзя")
+		return 0
+
+	case "list":
+		list, err := keys.List(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, "Ошибка получения ключей:", err)
+			return 1
+		}
+
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\tREVOKED")
+		for _, key := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix,
+				strings.Join(key.Scopes, ","), key.CreatedAt.Format(time.RFC3339),
+				formatTime(key.LastUsedAt), formatTime(key.RevokedAt))
+		}
+		w.Flush()
+		return 0
+
+	case "revoke":
+		if len(args) != 2 {
+			fmt.Fprint(stderr, apiKeyUsage)
+			return 2
+		}
+		id, err := uuid.Parse(args[1])
+		if err != nil {
+			fmt.Fprintln(stderr, "Неверный формат ID ключа:", err)
+			return 2
+		}
+
+		if err := keys.Revoke(ctx, id); err != nil {
+			fmt.Fprintln(stderr, "Ошибка отзыва ключа:", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "Ключ отозван:", id)
+		return 0
+	}
+
+	fmt.Fprint(stderr, apiKeyUsage)
+	return 2
+}
+
+func splitScopes(value string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(value, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
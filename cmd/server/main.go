@@ -2,19 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"subscription_service/pkg/auth"
 	"subscription_service/pkg/config"
 	"subscription_service/pkg/handler"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/metrics"
+	"subscription_service/pkg/middleware"
 	"subscription_service/pkg/service"
+	"subscription_service/pkg/tracing"
+	"syscall"
 	"time"
 
 	_ "subscription_service/docs"
+	"subscription_service/pkg/ratelimit"
 	"subscription_service/pkg/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -25,27 +38,57 @@ import (
 // @description     REST‑сервис для учёта онлайн‑подписок пользователей.
 // @host localhost:8080
 // @BasePath        /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <токен>"
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Ключ API внутреннего сервиса
 
 var logger = logrus.New()
 var cfg = config.LoadConfig(logger)
 
 func main() {
 
+	// Подкоманда управления ключами API
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		os.Exit(runAPIKeyCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Инициализация логгера
-	openLogFiles, err := InitLogrus()
+	logOutput, err := logging.Setup(logger, cfg.Log)
 	if err != nil {
-		logger.Error("Ошибка при настройке параметров логгера. Вывод всех ошибок будет осуществлён в консоль")
+		logger.WithError(err).Error("Ошибка при настройке параметров логгера. Вывод всех ошибок будет осуществлён в консоль")
 	} else {
-		// Закрытие всех открытых файлов в результате настройки логгера
-		defer openLogFiles.Close()
+		// Закрытие файла логов при завершении работы
+		defer logOutput.Close()
+		go reopenLogsOnSIGHUP(logOutput)
+	}
+
+	// Трассировка
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing, logger)
+	if err != nil {
+		logger.Fatal("Ошибка настройки трассировки: ", err)
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("Ошибка отправки трассировок: ", err)
+		}
+	}()
 
 	// Создание нового подключения к БД
 	pool, connStr, err := connectToDB()
 	if err != nil {
 		logger.Fatal("Ошибка подключения к БД: ", err)
 	}
-	defer pool.Close()
+	defer func() {
+		pool.Close()
+		logger.Info("Соединения с БД закрыты")
+	}()
 
 	// Миграция БД
 	err = repository.MigrateDB(connStr)
@@ -59,52 +102,172 @@ func main() {
 		logger.Info("Выполнена миграция БД")
 	}
 
+	migrationVersion, err := repository.LatestMigrationVersion()
+	if err != nil {
+		logger.Fatal("Ошибка чтения миграций: ", err)
+	}
+
 	// Инициализация зависимостей
+	appMetrics := metrics.New()
+
+	policy := service.DefaultPolicy(logger)
+	if cfg.PolicyFile != "" {
+		policy, err = service.LoadPolicy(cfg.PolicyFile, logger)
+		if err != nil {
+			logger.Fatal("Ошибка загрузки политики доступа: ", err)
+		}
+	}
+
 	subRepo := repository.NewSubRepo(pool, logger)
-	subService := service.NewSubService(subRepo, logger)
-	subHandler := handler.NewSubHandler(subService, logger)
+	subService := service.NewSubService(tracing.Repository(appMetrics.Repository(subRepo)), policy, cfg.RequireIfMatch, logger)
+	subHandler := handler.NewSubHandler(tracing.Service(subService), logger)
 
-	r := setRouter(subHandler)
+	auditService := service.NewAuditService(repository.NewAuditRepo(pool, logger), policy, logger)
+	auditHandler := handler.NewAuditHandler(auditService, logger)
 
-	logger.Info("Запуск сервера на порту ", 8080)
-	err = r.Run(":" + "8080")
-	if err != nil {
-		logger.Fatal("Ошибка запуска сервера: ", err)
+	healthRepo := repository.NewHealthRepo(pool, logger)
+	healthService := service.NewHealthService(healthRepo, migrationVersion, logger)
+	healthHandler := handler.NewHealthHandler(healthService, logger)
+
+	appMetrics.Register(
+		metrics.NewPoolCollector(pool),
+		metrics.NewBusinessCollector(subRepo, logger),
+	)
+
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepo(pool, logger), logger)
+
+	idempotencyRepo := repository.NewIdempotencyRepo(pool, logger)
+	idempotency := middleware.Idempotency(service.NewIdempotencyService(idempotencyRepo, cfg.IdempotencyTTL, cfg.IdempotencyLockTimeout, logger), logger)
+
+	var authenticate gin.HandlerFunc
+	if cfg.Auth.Enabled {
+		verifier, err := auth.NewVerifier(cfg.Auth)
+		if errors.Is(err, auth.ErrNoVerificationKeys) {
+			logger.Warn("Ключи проверки JWT не заданы, API доступно только с ключами API")
+		} else if err != nil {
+			logger.Fatal("Ошибка настройки аутентификации: ", err)
+		}
+		authenticate = middleware.Authenticate(verifier, apiKeyService, logger)
+	} else {
+		logger.Warn("Аутентификация отключена, API доступно без токена")
+	}
+
+	var ipRateLimit, rateLimit gin.HandlerFunc
+	if cfg.RateLimit.Enabled {
+		store := ratelimit.NewMemoryStore()
+		ipRateLimit = middleware.IPRateLimit(store, cfg.RateLimit.IP, logger)
+		rateLimit = middleware.RateLimit(store, cfg.RateLimit, logger)
+	}
+
+	r := setRouter(subHandler, auditHandler, healthHandler, appMetrics, ipRateLimit, authenticate, rateLimit, idempotency)
+	servers := []*http.Server{newServer(cfg.HTTP.Addr(), r)}
+
+	// Метрики отдаются только на отдельном порту, который не публикуется наружу
+	if cfg.HTTP.MetricsPort != 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", appMetrics.Handler())
+		servers = append(servers, newServer(cfg.HTTP.MetricsAddr(), mux))
 	}
-}
 
-func InitLogrus() (file *os.File, err error) {
-	// установим уровень логирования
-	switch cfg.LogLevel {
-	case "trace":
-		logger.SetLevel(logrus.TraceLevel)
-	case "debug":
-		logger.SetLevel(logrus.DebugLevel)
-	case "info":
-		logger.SetLevel(logrus.InfoLevel)
-	case "warn":
-		logger.SetLevel(logrus.WarnLevel)
-	case "error":
-		logger.SetLevel(logrus.ErrorLevel)
-	case "fatal":
-		logger.SetLevel(logrus.FatalLevel)
-	case "panic":
-		logger.SetLevel(logrus.PanicLevel)
-	default:
-		logger.SetLevel(logrus.InfoLevel)
-	}
-
-	// установим форматирование логов в джейсоне
-	logger.SetFormatter(&logrus.JSONFormatter{})
-
-	// установим вывод логов в файл
-	file, err = os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err == nil {
-		logger.SetOutput(file)
-		return file, nil
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Окончательное удаление подписок и просроченных ключей идемпотентности завершается до закрытия пула соединений
+	purgeDone := make(chan struct{})
+	if cfg.Purge.Interval > 0 {
+		purger := service.NewPurger(appMetrics.Repository(subRepo), idempotencyRepo, cfg.Purge.Retention, cfg.Purge.Interval, logger)
+		go func() {
+			defer close(purgeDone)
+			purger.Run(ctx)
+		}()
 	} else {
-		logger.Warn("Не удалось открыть файл логов, используется стандартный stderr")
-		return nil, err
+		close(purgeDone)
+	}
+
+	if err := runServers(ctx, healthHandler, servers...); err != nil {
+		logger.Error("Ошибка работы сервера: ", err)
+	}
+	stop()
+	<-purgeDone
+}
+
+// newServer создаёт HTTP-сервер с параметрами из конфигурации
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		ErrorLog:          log.New(logger.WriterLevel(logrus.ErrorLevel), "", 0),
+	}
+}
+
+// runServers запускает серверы и ожидает отмены ctx (сигнала SIGINT/SIGTERM).
+// После отмены серверы перестают принимать соединения и дожидаются завершения
+// обрабатываемых запросов не дольше cfg.HTTP.ShutdownTimeout.
+// Сервис отмечается готовым в health только после того, как все серверы заняли свои адреса
+func runServers(ctx context.Context, health *handler.HealthHandler, servers ...*http.Server) error {
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, server := range servers {
+		ln, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return fmt.Errorf("запуск сервера %s: %w", server.Addr, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	serverErr := make(chan error, len(servers))
+	for i, server := range servers {
+		go func() {
+			logger.Info("Запуск сервера на адресе ", server.Addr)
+			serverErr <- server.Serve(listeners[i])
+		}()
+	}
+	health.SetReady(true)
+
+	var runErr error
+	select {
+	case runErr = <-serverErr:
+		logger.Error("Сервер завершил работу с ошибкой: ", runErr)
+	case <-ctx.Done():
+		logger.Info("Получен сигнал остановки, завершение обработки запросов")
+	}
+	health.SetReady(false)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+
+	errs := []error{runErr}
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			// Не уложились в отведённое время, оставшиеся соединения закрываются принудительно
+			server.Close()
+			errs = append(errs, fmt.Errorf("остановка сервера %s: %w", server.Addr, err))
+		}
+	}
+
+	logger.Info("Сервер остановлен")
+	return errors.Join(errs...)
+}
+
+// reopenLogsOnSIGHUP переоткрывает файл логов по сигналу SIGHUP,
+// после того как внешний logrotate переименовал его
+func reopenLogsOnSIGHUP(output *logging.Output) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := output.Reopen(); err != nil {
+			logger.WithError(err).Error("Ошибка переоткрытия файла логов")
+			continue
+		}
+		logger.Info("Файл логов переоткрыт")
 	}
 }
 
@@ -116,6 +279,7 @@ func connectToDB() (*pgxpool.Pool, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	confPool.ConnConfig.Tracer = tracing.QueryTracer{}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -134,41 +298,75 @@ func connectToDB() (*pgxpool.Pool, string, error) {
 	return pool, connStr, nil
 }
 
-func setRouter(handler *handler.Handler) *gin.Engine {
+func setRouter(handler *handler.Handler, audit *handler.AuditHandler, health *handler.HealthHandler, appMetrics *metrics.Metrics, ipRateLimit, authenticate, rateLimit, idempotency gin.HandlerFunc) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	rout := gin.New()
-	rout.Use(gin.Recovery())
-	rout.Use(loggingMiddleware())
+	if err := rout.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		logger.Fatal("Неверный список доверенных прокси: ", err)
+	}
+	rout.Use(middleware.RequestID())
+	rout.Use(appMetrics.Middleware())
+	rout.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithGinFilter(tracedRoute)))
+	rout.Use(middleware.Language(cfg.DefaultLanguage))
+	rout.Use(middleware.Logging(logger))
+	rout.Use(middleware.Recovery(logger))
+	rout.Use(middleware.ErrorHandler(logger))
+	rout.NoRoute(middleware.NoRoute())
+
+	// Проверки состояния
+	rout.GET("/healthz", health.Liveness)
+	rout.GET("/readyz", health.Readiness)
 
 	// Swagger
 	rout.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Маршруты
-	sub := rout.Group("/subscriptions")
+	api := rout.Group("")
+	// Ограничение по IP-адресу до аутентификации учитывает и запросы с неверными учётными данными
+	if ipRateLimit != nil {
+		api.Use(ipRateLimit)
+	}
+	if authenticate != nil {
+		api.Use(authenticate)
+	}
+	if rateLimit != nil {
+		api.Use(rateLimit)
+	}
+	api.Use(idempotency)
+	sub := api.Group("/subscriptions")
+
+	// Области доступа, которые должны быть разрешены ключу API для маршрута
+	read := middleware.RequireScope(auth.ScopeSubscriptionsRead)
+	write := middleware.RequireScope(auth.ScopeSubscriptionsWrite)
+	reports := middleware.RequireScope(auth.ScopeReportsRead)
 	{
-		sub.POST("", handler.CreateSubscription)
-		sub.GET("", handler.ListSubscriptions)
-		sub.GET("/:user_id/:service_name", handler.GetSubscription)
-		sub.PUT("/:user_id/:service_name", handler.UpdateSubscription)
-		sub.DELETE("/:user_id/:service_name", handler.DeleteSubscription)
-		sub.GET("/total", handler.GetTotalSubscriptions)
+		sub.POST("", write, handler.CreateSubscription)
+		sub.GET("", read, handler.ListSubscriptions)
+		sub.POST("/import", write, handler.ImportSubscriptions)
+		sub.GET("/:user_id/:service_name", read, handler.GetSubscription)
+		sub.PUT("/:user_id/:service_name", write, handler.UpdateSubscription)
+		sub.PATCH("/:user_id/:service_name", write, handler.PatchSubscription)
+		sub.DELETE("/:user_id/:service_name", write, handler.DeleteSubscription)
+		sub.POST("/:user_id/:service_name/restore", write, handler.RestoreSubscription)
+		sub.GET("/:user_id/:service_name/history", read, audit.History)
+		sub.GET("/by-id/:id", read, handler.GetSubscriptionByID)
+		sub.PUT("/by-id/:id", write, handler.UpdateSubscriptionByID)
+		sub.PATCH("/by-id/:id", write, handler.PatchSubscriptionByID)
+		sub.DELETE("/by-id/:id", write, handler.DeleteSubscriptionByID)
+		sub.POST("/by-id/:id/restore", write, handler.RestoreSubscriptionByID)
+		sub.GET("/total", reports, handler.GetTotalSubscriptions)
+		sub.GET("/total/breakdown", reports, handler.GetTotalBreakdown)
 	}
+
+	api.GET("/audit", middleware.RequireScope(auth.ScopeAuditRead), audit.List)
 	return rout
 }
 
-func loggingMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-		c.Next()
-		duration := time.Since(start)
-
-		logger.WithFields(logrus.Fields{
-			"method":     c.Request.Method,
-			"path":       c.Request.URL.Path,
-			"status":     c.Writer.Status(),
-			"duration":   duration,
-			"client_ip":  c.ClientIP(),
-			"user_agent": c.Request.UserAgent(),
-		}).Info("HTTP request")
+// tracedRoute исключает из трассировки служебные маршруты
+func tracedRoute(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/healthz", "/readyz", "/swagger/*any":
+		return false
 	}
+	return true
 }
@@ -0,0 +1,10 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS id uuid NOT NULL DEFAULT gen_random_uuid();
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'subscriptions_pkey') THEN
+        ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_pkey PRIMARY KEY (id);
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS subscriptions_user_id_service_name_key ON subscriptions(user_id, service_name);
\ No newline at end of file
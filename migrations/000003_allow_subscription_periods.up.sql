@@ -0,0 +1,14 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+DROP INDEX IF EXISTS subscriptions_user_id_service_name_key;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'subscriptions_periods_excl') THEN
+        ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_periods_excl EXCLUDE USING gist (
+            user_id WITH =,
+            service_name WITH =,
+            daterange(start_date, end_date, '[]') WITH &&
+        );
+    END IF;
+END $$;
\ No newline at end of file
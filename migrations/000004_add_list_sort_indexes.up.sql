@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS subscriptions_start_date_id_idx ON subscriptions(start_date, id);
+CREATE INDEX IF NOT EXISTS subscriptions_price_id_idx ON subscriptions(price, id);
+CREATE INDEX IF NOT EXISTS subscriptions_service_name_id_idx ON subscriptions(service_name, id);
\ No newline at end of file
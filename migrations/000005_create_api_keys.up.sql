@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL,
+    -- Начало ключа для опознания в списке, сам ключ хранится только в виде SHA-256
+    prefix text NOT NULL,
+    key_hash text NOT NULL UNIQUE,
+    scopes text[] NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    last_used_at timestamptz,
+    revoked_at timestamptz
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id uuid NOT NULL,
+    -- Владелец и сервис подписки, чтобы история не зависела от её удаления
+    user_id uuid NOT NULL,
+    service_name text NOT NULL,
+    -- Кто выполнил изменение: user (actor_id - пользователь), api_key (actor_id - ключ API)
+    -- или anonymous при отключённой аутентификации
+    actor_type text NOT NULL,
+    actor_id uuid,
+    action text NOT NULL,
+    before jsonb,
+    after jsonb,
+    request_id text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_subscription ON audit_log (user_id, service_name, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at DESC);
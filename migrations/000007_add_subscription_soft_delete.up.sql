@@ -0,0 +1,11 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+-- Удалённые периоды не мешают создавать новые периоды той же подписки
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_periods_excl;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_periods_excl EXCLUDE USING gist (
+    user_id WITH =,
+    service_name WITH =,
+    daterange(start_date, end_date, '[]') WITH &&
+) WHERE (deleted_at IS NULL);
+
+CREATE INDEX IF NOT EXISTS subscriptions_deleted_at_idx ON subscriptions(deleted_at) WHERE deleted_at IS NOT NULL;
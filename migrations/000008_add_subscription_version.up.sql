@@ -0,0 +1,2 @@
+-- Версия подписки для оптимистичной блокировки, увеличивается при каждом изменении
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    -- Клиент, передавший ключ (ключ API, пользователь или IP-адрес), ключи разных клиентов не пересекаются
+    client_key text NOT NULL,
+    idempotency_key text NOT NULL,
+    -- SHA-256 метода, адреса и тела запроса, по нему повтор отличается от другого запроса с тем же ключом
+    request_hash text NOT NULL,
+    -- Сохранённый ответ, status_code NULL - запрос ещё выполняется
+    status_code integer,
+    headers jsonb,
+    body bytea,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (client_key, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,3 @@
+-- Уникальна только действующая (бессрочная) подписка, завершённая или удалённая не мешает подписаться на сервис снова
+CREATE UNIQUE INDEX IF NOT EXISTS subscriptions_user_id_service_name_active_key ON subscriptions(user_id, service_name)
+    WHERE end_date IS NULL AND deleted_at IS NULL;
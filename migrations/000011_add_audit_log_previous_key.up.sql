@@ -0,0 +1,13 @@
+-- Владелец и сервис подписки до изменения, если изменение их поменяло,
+-- чтобы изменение попадало и в историю прежней подписки
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS previous_user_id uuid;
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS previous_service_name text;
+
+UPDATE audit_log
+SET previous_user_id = (before->>'user_id')::uuid,
+    previous_service_name = before->>'service_name'
+WHERE before IS NOT NULL AND after IS NOT NULL AND previous_user_id IS NULL
+  AND ((before->>'user_id')::uuid <> user_id OR before->>'service_name' <> service_name);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_previous_subscription ON audit_log (previous_user_id, previous_service_name, created_at DESC)
+    WHERE previous_user_id IS NOT NULL;
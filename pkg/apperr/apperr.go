@@ -0,0 +1,155 @@
+package apperr
+
+import (
+	"errors"
+	"strings"
+	"subscription_service/pkg/model"
+)
+
+// Категории ошибок предметной области. Проверяются через errors.Is
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrValidation           = errors.New("validation failed")
+	ErrUnprocessable        = errors.New("unprocessable entity")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrTooManyRequests      = errors.New("too many requests")
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+	ErrRequestTooLarge      = errors.New("request too large")
+)
+
+// Error - ошибка предметной области с сообщением для клиента и дополнительными данными
+type Error struct {
+	// Kind - одна из категорий ErrNotFound, ErrConflict и т.д.
+	Kind error
+	// Code - стабильный код ошибки, например SUBSCRIPTION_EXISTS
+	Code string
+	// Message - описание ошибки для клиента
+	Message string
+	// Param - значение, подставляемое в перевод сообщения вместо {param}
+	Param string
+	// Fields - ошибки по отдельным полям запроса
+	Fields []model.FieldError
+	// Conflict - существующая подписка, с которой конфликтует запрос
+	Conflict *model.Subscription
+	// Err - исходная ошибка
+	Err error
+}
+
+func New(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Wrap(kind error, code, message string, err error) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
+
+func NotFound(code, message string) *Error {
+	return New(ErrNotFound, code, message)
+}
+
+func Conflict(code, message string, conflict *model.Subscription) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message, Conflict: conflict}
+}
+
+// Validation создаёт ошибку формата входных данных, поля добавляются методом Add
+func Validation(code, message string) *Error {
+	return New(ErrValidation, code, message)
+}
+
+// Unprocessable создаёт ошибку для корректных по формату данных, нарушающих правила предметной области
+func Unprocessable(code, message string) *Error {
+	return New(ErrUnprocessable, code, message)
+}
+
+// Unauthorized создаёт ошибку отсутствующих или неверных учётных данных
+func Unauthorized(code, message string) *Error {
+	return New(ErrUnauthorized, code, message)
+}
+
+// Forbidden создаёт ошибку запрета доступа к ресурсу
+func Forbidden(code, message string) *Error {
+	return New(ErrForbidden, code, message)
+}
+
+// PreconditionFailed создаёт ошибку несовпадения условия запроса (If-Match) с текущим состоянием ресурса
+func PreconditionFailed(code, message string) *Error {
+	return New(ErrPreconditionFailed, code, message)
+}
+
+// PreconditionRequired создаёт ошибку изменения ресурса без обязательного условия If-Match
+func PreconditionRequired(code, message string) *Error {
+	return New(ErrPreconditionRequired, code, message)
+}
+
+// TooManyRequests создаёт ошибку превышения допустимой частоты запросов
+func TooManyRequests(code, message string) *Error {
+	return New(ErrTooManyRequests, code, message)
+}
+
+// RequestTooLarge создаёт ошибку превышения допустимого размера тела запроса
+func RequestTooLarge(code, message string) *Error {
+	return New(ErrRequestTooLarge, code, message)
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.Error())
+	if e.Code != "" {
+		b.WriteString(" [" + e.Code + "]")
+	}
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	for _, field := range e.Fields {
+		b.WriteString("; " + field.Field + ": " + field.Message)
+	}
+	if e.Err != nil {
+		b.WriteString(": " + e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// WithParam задаёт значение, которое подставляется в перевод сообщения,
+// например текущую версию подписки
+func (e *Error) WithParam(param string) *Error {
+	e.Param = param
+	return e
+}
+
+// Add добавляет ошибку по полю field с кодом code
+func (e *Error) Add(field, code, message string) *Error {
+	e.Fields = append(e.Fields, model.FieldError{Field: field, Code: code, Message: message})
+	return e
+}
+
+// AddParam добавляет ошибку по полю field с параметром правила проверки,
+// например минимальным значением или списком допустимых значений
+func (e *Error) AddParam(field, code, param, message string) *Error {
+	e.Fields = append(e.Fields, model.FieldError{Field: field, Code: code, Param: param, Message: message})
+	return e
+}
+
+// HasErrors сообщает, добавлена ли хотя бы одна ошибка по полю
+func (e *Error) HasErrors() bool {
+	return len(e.Fields) > 0
+}
+
+// As возвращает *Error из цепочки ошибок err
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,56 @@
+package apperr
+
+// Стабильные коды ошибок, на которые могут опираться клиенты
+const (
+	CodeInternal                = "INTERNAL_ERROR"
+	CodeRouteNotFound           = "ROUTE_NOT_FOUND"
+	CodeSubscriptionNotFound    = "SUBSCRIPTION_NOT_FOUND"
+	CodeSubscriptionExists      = "SUBSCRIPTION_EXISTS"
+	CodeSubscriptionNotDeleted  = "SUBSCRIPTION_NOT_DELETED"
+	CodeInvalidRequestBody      = "INVALID_REQUEST_BODY"
+	CodeInvalidQueryParams      = "INVALID_QUERY_PARAMS"
+	CodeInvalidUserID           = "INVALID_USER_ID"
+	CodeInvalidSubscriptionID   = "INVALID_SUBSCRIPTION_ID"
+	CodeInvalidDate             = "INVALID_DATE"
+	CodeInvalidPeriod           = "INVALID_PERIOD"
+	CodeInvalidPriceRange       = "INVALID_PRICE_RANGE"
+	CodeValidationFailed        = "VALIDATION_FAILED"
+	CodePathMismatch            = "PATH_MISMATCH"
+	CodeConstraintViolation     = "CONSTRAINT_VIOLATION"
+	CodeUnsupportedMediaType    = "UNSUPPORTED_MEDIA_TYPE"
+	CodeUnauthorized            = "UNAUTHORIZED"
+	CodeInvalidToken            = "INVALID_TOKEN"
+	CodeAccessDenied            = "ACCESS_DENIED"
+	CodeActionNotPermitted      = "ACTION_NOT_PERMITTED"
+	CodeInvalidAPIKey           = "INVALID_API_KEY"
+	CodeAPIKeyRequired          = "API_KEY_REQUIRED"
+	CodeAPIKeyNotFound          = "API_KEY_NOT_FOUND"
+	CodeInsufficientScope       = "INSUFFICIENT_SCOPE"
+	CodeRateLimitExceeded       = "RATE_LIMIT_EXCEEDED"
+	CodeVersionMismatch         = "VERSION_MISMATCH"
+	CodeIfMatchRequired         = "IF_MATCH_REQUIRED"
+	CodeInvalidIdempotencyKey   = "INVALID_IDEMPOTENCY_KEY"
+	CodeRequestTooLarge         = "REQUEST_TOO_LARGE"
+	CodeIdempotencyKeyReused    = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInProgress   = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeInvalidImportFile       = "INVALID_IMPORT_FILE"
+	CodeUnsupportedImportFormat = "UNSUPPORTED_IMPORT_FORMAT"
+)
+
+// Коды ошибок отдельных полей запроса
+const (
+	FieldRequired         = "REQUIRED"
+	FieldTooSmall         = "TOO_SMALL"
+	FieldTooLarge         = "TOO_LARGE"
+	FieldInvalidType      = "INVALID_TYPE"
+	FieldInvalidValue     = "INVALID_VALUE"
+	FieldInvalidUUID      = "INVALID_UUID"
+	FieldInvalidDate      = "INVALID_DATE_FORMAT"
+	FieldInvalidTimestamp = "INVALID_TIMESTAMP_FORMAT"
+	FieldEndBeforeStart   = "END_BEFORE_START"
+	FieldNotAllowed       = "NOT_ALLOWED"
+	FieldInvalidCursor    = "INVALID_CURSOR"
+	FieldMaxLessThanMin   = "MAX_LESS_THAN_MIN"
+	FieldScopeRequired    = "SCOPE_REQUIRED"
+	FieldOverlapsLine     = "OVERLAPS_LINE"
+)
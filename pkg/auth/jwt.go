@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"subscription_service/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// minHS256SecretLength - минимальная длина общего секрета HS256 (256 бит)
+const minHS256SecretLength = 32
+
+// weakHS256Secrets - общеизвестные значения секрета из примеров конфигурации
+var weakHS256Secrets = map[string]bool{
+	"change-me": true,
+	"changeme":  true,
+	"secret":    true,
+}
+
+// ErrNoVerificationKeys - в конфигурации не задан ни один ключ проверки подписи JWT.
+// В этом случае сервис принимает только ключи API
+var ErrNoVerificationKeys = errors.New("no JWT verification keys configured")
+
+// Verifier проверяет подпись и срок действия JWT и извлекает из них пользователя
+type Verifier struct {
+	hmacSecret []byte
+	// Открытые ключи RS256 по идентификатору kid, ключ из PEM-файла хранится под пустым kid
+	rsaKeys    map[string]*rsa.PublicKey
+	rolesClaim string
+	parser     *jwt.Parser
+}
+
+// NewVerifier загружает ключи проверки подписи из конфигурации:
+// общий секрет HS256, открытый ключ RS256 в формате PEM и/или локальный файл JWKS
+func NewVerifier(cfg config.AuthConfig) (*Verifier, error) {
+	v := &Verifier{rsaKeys: map[string]*rsa.PublicKey{}, rolesClaim: cfg.RolesClaim}
+
+	var methods []string
+	if cfg.HS256Secret != "" {
+		if err := validateHS256Secret(cfg.HS256Secret); err != nil {
+			return nil, err
+		}
+		v.hmacSecret = []byte(cfg.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if cfg.RS256PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.RS256PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read public key: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		v.rsaKeys[""] = key
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("load JWKS: %w", err)
+		}
+		for kid, key := range keys {
+			v.rsaKeys[kid] = key
+		}
+	}
+
+	if len(v.rsaKeys) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, ErrNoVerificationKeys
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// validateHS256Secret отклоняет общеизвестные и слишком короткие секреты,
+// с которыми любой может подписать токен от имени произвольного пользователя
+func validateHS256Secret(secret string) error {
+	if weakHS256Secrets[strings.ToLower(strings.TrimSpace(secret))] {
+		return errors.New("JWT_HS256_SECRET is set to a well-known placeholder value")
+	}
+	if len(secret) < minHS256SecretLength {
+		return fmt.Errorf("JWT_HS256_SECRET must be at least %d bytes long", minHS256SecretLength)
+	}
+	return nil
+}
+
+// Verify проверяет токен и возвращает пользователя из claim sub и списка ролей
+func (v *Verifier) Verify(tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.key); err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("subject is not a UUID: %w", err)
+	}
+
+	return &Principal{UserID: userID, Roles: rolesFromClaim(claims[v.rolesClaim])}, nil
+}
+
+// key выбирает ключ проверки подписи по алгоритму и kid из заголовка токена
+func (v *Verifier) key(token *jwt.Token) (any, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.hmacSecret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		// Токен без kid проверяется единственным ключом, если он один
+		if kid == "" && len(v.rsaKeys) == 1 {
+			for _, key := range v.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+// rolesFromClaim читает роли из массива строк или из строки с ролями через пробел
+func rolesFromClaim(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		roles := make([]string, 0, len(value))
+		for _, role := range value {
+			if s, ok := role.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+// jwk - открытый ключ RSA в формате JSON Web Key (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadJWKS читает ключи RSA для проверки подписи из файла JWKS
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: exponent: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no RS256 signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"subscription_service/pkg/config"
+	"testing"
+)
+
+func TestNewVerifierHS256Secret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"значение из примера", "change-me", true},
+		{"значение из примера в другом регистре", "Change-Me", true},
+		{"короткий секрет", "short-secret", true},
+		{"на байт короче минимума", strings.Repeat("a", minHS256SecretLength-1), true},
+		{"достаточно длинный секрет", strings.Repeat("s", minHS256SecretLength), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(config.AuthConfig{HS256Secret: tt.secret})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewVerifier() error = %v, ожидалась ошибка: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewVerifierWithoutKeys(t *testing.T) {
+	// Без ключей проверки подписи сервис принимает только ключи API
+	_, err := NewVerifier(config.AuthConfig{})
+	if !errors.Is(err, ErrNoVerificationKeys) {
+		t.Errorf("NewVerifier() error = %v, ожидалась %v", err, ErrNoVerificationKeys)
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// RoleAdmin - роль с доступом к подпискам всех пользователей
+const RoleAdmin = "admin"
+
+// Области доступа ключей API
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeReportsRead        = "reports:read"
+	ScopeAuditRead          = "audit:read"
+)
+
+// Scopes - все области доступа ключей API
+var Scopes = []string{ScopeSubscriptionsRead, ScopeSubscriptionsWrite, ScopeReportsRead, ScopeAuditRead}
+
+// Principal - аутентифицированный пользователь или внутренний сервис, выполняющий запрос
+type Principal struct {
+	// UserID - пользователь из claim sub токена, для ключа API не задан
+	UserID uuid.UUID
+	Roles  []string
+	// APIKeyID и Scopes заданы для запросов внутренних сервисов с ключом API
+	APIKeyID *uuid.UUID
+	Scopes   []string
+}
+
+// IsService сообщает, что запрос выполняет внутренний сервис с ключом API
+func (p *Principal) IsService() bool {
+	return p.APIKeyID != nil
+}
+
+// HasScope сообщает, разрешена ли ключу API область доступа scope.
+// Для пользователей с JWT области доступа не применяются
+func (p *Principal) HasScope(scope string) bool {
+	return !p.IsService() || slices.Contains(p.Scopes, scope)
+}
+
+// HasRole сообщает, есть ли у пользователя роль role
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// IsAdmin сообщает, есть ли у пользователя роль администратора
+func (p *Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+type principalKey struct{}
+
+// WithPrincipal сохраняет пользователя в контексте запроса
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext возвращает пользователя из контекста запроса.
+// Если аутентификация отключена, пользователя в контексте нет
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
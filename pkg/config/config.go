@@ -3,6 +3,9 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"subscription_service/pkg/i18n"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -15,8 +18,145 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
-	LogLevel   string
-	LogFile    string
+	Log        LogConfig
+	// Язык сообщений об ошибках, если клиент не передал поддерживаемый Accept-Language
+	DefaultLanguage string
+	HTTP            HTTPConfig
+	Tracing         TracingConfig
+	Auth            AuthConfig
+	// YAML-файл политики доступа по ролям, пустое значение - встроенная политика по умолчанию
+	PolicyFile string
+	RateLimit  RateLimitConfig
+	Purge      PurgeConfig
+	// Изменение и удаление подписки требуют заголовок If-Match с её ETag.
+	// По умолчанию выключено, чтобы не ломать клиентов, которые не передают заголовок
+	RequireIfMatch bool
+	// Срок хранения ответов на запросы с заголовком Idempotency-Key
+	IdempotencyTTL time.Duration
+	// Время, после которого ключ идемпотентности незавершённого запроса можно занять снова.
+	// Должно превышать время обработки самого долгого запроса
+	IdempotencyLockTimeout time.Duration
+}
+
+// PurgeConfig - окончательное удаление подписок, помеченных удалёнными
+type PurgeConfig struct {
+	// Срок хранения удалённых подписок
+	Retention time.Duration
+	// Интервал запуска удаления, 0 - удаление отключено
+	Interval time.Duration
+}
+
+// RateLimitConfig - ограничение частоты запросов клиентов к API
+type RateLimitConfig struct {
+	Enabled bool
+	// Ограничение всех запросов с одного IP-адреса, проверяется до аутентификации
+	IP RateLimit
+	// Ограничение для маршрутов без собственного ограничения
+	Default RateLimit
+	// Ограничения отдельных маршрутов по ключу "МЕТОД /путь", например "GET /subscriptions"
+	Routes map[string]RateLimit
+}
+
+// RateLimit - не более Requests запросов за Period
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// AuthConfig - параметры аутентификации по JWT
+type AuthConfig struct {
+	Enabled bool
+	// Общий секрет для токенов HS256
+	HS256Secret string
+	// Открытый ключ для токенов RS256 в формате PEM
+	RS256PublicKeyFile string
+	// Локальный файл JWKS с открытыми ключами RS256
+	JWKSFile string
+	// Ожидаемые издатель (iss) и получатель (aud) токена, пустое значение - не проверяется
+	Issuer   string
+	Audience string
+	// Claim со списком ролей пользователя
+	RolesClaim string
+	// Допустимое расхождение часов при проверке срока действия
+	Leeway time.Duration
+}
+
+// HTTPConfig - параметры HTTP-сервера
+type HTTPConfig struct {
+	Port              int
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// Порт для отдачи метрик Prometheus, 0 - метрики не отдаются
+	MetricsPort int
+	// Время на завершение обрабатываемых запросов при остановке сервера
+	ShutdownTimeout time.Duration
+	// Адреса и подсети прокси, которым доверяется заголовок X-Forwarded-For.
+	// Пустой список - IP клиента берётся из соединения
+	TrustedProxies []string
+}
+
+// Addr возвращает адрес, на котором слушает сервер
+func (c HTTPConfig) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// MetricsAddr возвращает адрес сервера метрик
+func (c HTTPConfig) MetricsAddr() string {
+	return ":" + strconv.Itoa(c.MetricsPort)
+}
+
+// Приёмники логов
+const (
+	LogOutputStdout = "stdout"
+	LogOutputFile   = "file"
+	LogOutputBoth   = "both"
+)
+
+// Форматы логов
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// LogConfig - параметры логирования
+type LogConfig struct {
+	Level string
+	// Приёмник логов: stdout, file или both
+	Output string
+	// Формат записей: json или text
+	Format string
+	File   string
+	// Размер файла в мегабайтах, при превышении которого начинается новый файл
+	MaxSizeMB int
+	// Срок хранения и количество старых файлов, 0 - без ограничения
+	MaxAgeDays int
+	MaxBackups int
+	// Сжимать старые файлы gzip
+	Compress bool
+	// Начинать новый файл раз в интервал, 0 - только по размеру
+	RotateInterval time.Duration
+}
+
+// Экспортёры трассировок
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// TracingConfig - параметры трассировки OpenTelemetry
+type TracingConfig struct {
+	// Экспортёр: none, stdout или otlp
+	Exporter    string
+	ServiceName string
+	// Адрес OTLP/HTTP коллектора, например otel-collector:4318
+	OTLPEndpoint string
+	OTLPInsecure bool
+	// Доля запросов, для которых записываются трассировки (от 0 до 1)
+	SampleRatio float64
 }
 
 func LoadConfig(logger *logrus.Logger) *Config {
@@ -26,6 +166,12 @@ func LoadConfig(logger *logrus.Logger) *Config {
 
 	dbPort, _ := strconv.Atoi(getEnv("DB_PORT", "5432"))
 
+	defaultLanguage := getEnv("DEFAULT_LANGUAGE", i18n.Russian)
+	if !i18n.Supported(defaultLanguage) {
+		logger.Warnf("Язык %q не поддерживается, используется %q", defaultLanguage, i18n.Russian)
+		defaultLanguage = i18n.Russian
+	}
+
 	return &Config{
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     dbPort,
@@ -33,8 +179,67 @@ func LoadConfig(logger *logrus.Logger) *Config {
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "password"),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
-		LogFile:    getEnv("LOG_FILE", "logs/app.log"),
-		LogLevel:   getEnv("LOG_LEVEL", "info"),
+		Log: LogConfig{
+			Level:          getEnv("LOG_LEVEL", "info"),
+			Output:         getEnv("LOG_OUTPUT", LogOutputBoth),
+			Format:         getEnv("LOG_FORMAT", LogFormatJSON),
+			File:           getEnv("LOG_FILE", "logs/app.log"),
+			MaxSizeMB:      getEnvInt(logger, "LOG_MAX_SIZE_MB", 100),
+			MaxAgeDays:     getEnvInt(logger, "LOG_MAX_AGE_DAYS", 30),
+			MaxBackups:     getEnvInt(logger, "LOG_MAX_BACKUPS", 10),
+			Compress:       getEnvBool(logger, "LOG_COMPRESS", true),
+			RotateInterval: getEnvDuration(logger, "LOG_ROTATE_INTERVAL", 0),
+		},
+
+		DefaultLanguage: defaultLanguage,
+		HTTP: HTTPConfig{
+			Port:              getEnvInt(logger, "HTTP_PORT", 8080),
+			ReadTimeout:       getEnvDuration(logger, "HTTP_READ_TIMEOUT", 10*time.Second),
+			ReadHeaderTimeout: getEnvDuration(logger, "HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+			WriteTimeout:      getEnvDuration(logger, "HTTP_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:       getEnvDuration(logger, "HTTP_IDLE_TIMEOUT", 60*time.Second),
+			MaxHeaderBytes:    getEnvInt(logger, "HTTP_MAX_HEADER_BYTES", 1<<20),
+			MetricsPort:       getEnvInt(logger, "METRICS_PORT", 9090),
+			ShutdownTimeout:   getEnvDuration(logger, "HTTP_SHUTDOWN_TIMEOUT", 15*time.Second),
+			TrustedProxies:    getEnvList("HTTP_TRUSTED_PROXIES"),
+		},
+		Auth: AuthConfig{
+			Enabled:            getEnvBool(logger, "AUTH_ENABLED", true),
+			HS256Secret:        getEnv("JWT_HS256_SECRET", ""),
+			RS256PublicKeyFile: getEnv("JWT_RS256_PUBLIC_KEY_FILE", ""),
+			JWKSFile:           getEnv("JWT_JWKS_FILE", ""),
+			Issuer:             getEnv("JWT_ISSUER", ""),
+			Audience:           getEnv("JWT_AUDIENCE", ""),
+			RolesClaim:         getEnv("JWT_ROLES_CLAIM", "roles"),
+			Leeway:             getEnvDuration(logger, "JWT_LEEWAY", 30*time.Second),
+		},
+		PolicyFile: getEnv("POLICY_FILE", ""),
+		RateLimit: RateLimitConfig{
+			Enabled: getEnvBool(logger, "RATE_LIMIT_ENABLED", true),
+			IP: RateLimit{
+				Requests: getEnvPositiveInt(logger, "RATE_LIMIT_IP_REQUESTS", 300),
+				Period:   getEnvPositiveDuration(logger, "RATE_LIMIT_IP_PERIOD", time.Minute),
+			},
+			Default: RateLimit{
+				Requests: getEnvPositiveInt(logger, "RATE_LIMIT_REQUESTS", 100),
+				Period:   getEnvPositiveDuration(logger, "RATE_LIMIT_PERIOD", time.Minute),
+			},
+			Routes: getEnvRateLimits(logger, "RATE_LIMIT_ROUTES"),
+		},
+		RequireIfMatch:         getEnvBool(logger, "REQUIRE_IF_MATCH", false),
+		IdempotencyTTL:         getEnvPositiveDuration(logger, "IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLockTimeout: getEnvPositiveDuration(logger, "IDEMPOTENCY_LOCK_TIMEOUT", time.Minute),
+		Purge: PurgeConfig{
+			Retention: getEnvDuration(logger, "PURGE_RETENTION", 90*24*time.Hour),
+			Interval:  getEnvDuration(logger, "PURGE_INTERVAL", time.Hour),
+		},
+		Tracing: TracingConfig{
+			Exporter:     getEnv("TRACING_EXPORTER", TracingExporterNone),
+			ServiceName:  getEnv("TRACING_SERVICE_NAME", "subscription_service"),
+			OTLPEndpoint: getEnv("TRACING_OTLP_ENDPOINT", "localhost:4318"),
+			OTLPInsecure: getEnvBool(logger, "TRACING_OTLP_INSECURE", true),
+			SampleRatio:  getEnvFloat(logger, "TRACING_SAMPLE_RATIO", 1),
+		},
 	}
 }
 
@@ -44,3 +249,129 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt читает целое число из переменной окружения.
+// При неверном значении используется defaultValue
+func getEnvInt(logger *logrus.Logger, key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		logger.Warnf("Неверное значение %s=%q, используется %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvDuration читает длительность в формате time.ParseDuration (например, 10s, 1m30s).
+// При неверном значении используется defaultValue
+func getEnvDuration(logger *logrus.Logger, key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		logger.Warnf("Неверное значение %s=%q, используется %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvBool читает логическое значение (true/false, 1/0) из переменной окружения.
+// При неверном значении используется defaultValue
+func getEnvBool(logger *logrus.Logger, key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warnf("Неверное значение %s=%q, используется %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvFloat читает неотрицательное число из переменной окружения.
+// При неверном значении используется defaultValue
+func getEnvFloat(logger *logrus.Logger, key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		logger.Warnf("Неверное значение %s=%q, используется %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvPositiveInt читает положительное целое число из переменной окружения.
+// При неверном значении используется defaultValue
+func getEnvPositiveInt(logger *logrus.Logger, key string, defaultValue int) int {
+	value := getEnvInt(logger, key, defaultValue)
+	if value == 0 {
+		logger.Warnf("Неверное значение %s=0, используется %d", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvPositiveDuration читает положительную длительность из переменной окружения.
+// При неверном значении используется defaultValue
+func getEnvPositiveDuration(logger *logrus.Logger, key string, defaultValue time.Duration) time.Duration {
+	value := getEnvDuration(logger, key, defaultValue)
+	if value == 0 {
+		logger.Warnf("Неверное значение %s=0, используется %s", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList читает список значений через запятую из переменной окружения
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvRateLimits читает ограничения маршрутов в формате
+// "GET /subscriptions=20/1m, GET /subscriptions/total=10/1m".
+// Записи в неверном формате пропускаются
+func getEnvRateLimits(logger *logrus.Logger, key string) map[string]RateLimit {
+	limits := make(map[string]RateLimit)
+	for _, item := range getEnvList(key) {
+		route, limit, found := strings.Cut(item, "=")
+		requests, period, ok := strings.Cut(limit, "/")
+		if !found || !ok {
+			logger.Warnf("Неверное ограничение маршрута %s: %q", key, item)
+			continue
+		}
+
+		parsedRequests, err := strconv.Atoi(strings.TrimSpace(requests))
+		if err != nil || parsedRequests <= 0 {
+			logger.Warnf("Неверное ограничение маршрута %s: %q", key, item)
+			continue
+		}
+		parsedPeriod, err := time.ParseDuration(strings.TrimSpace(period))
+		if err != nil || parsedPeriod <= 0 {
+			logger.Warnf("Неверное ограничение маршрута %s: %q", key, item)
+			continue
+		}
+
+		limits[strings.Join(strings.Fields(route), " ")] = RateLimit{Requests: parsedRequests, Period: parsedPeriod}
+	}
+	return limits
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestGetEnvRateLimits(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	t.Setenv("RATE_LIMIT_ROUTES", "GET /subscriptions=30/1m, POST  /subscriptions/import = 5/1h,GET /a=0/1m,GET /b=5,GET /c=5/0s,GET /d=x/1m")
+
+	got := getEnvRateLimits(logger, "RATE_LIMIT_ROUTES")
+	want := map[string]RateLimit{
+		"GET /subscriptions":         {Requests: 30, Period: time.Minute},
+		"POST /subscriptions/import": {Requests: 5, Period: time.Hour},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("getEnvRateLimits() = %v, ожидалось %v", got, want)
+	}
+	for route, limit := range want {
+		if got[route] != limit {
+			t.Errorf("ограничение %q = %+v, ожидалось %+v", route, got[route], limit)
+		}
+	}
+	if warnings := len(hook.AllEntries()); warnings != 4 {
+		t.Errorf("записано %d предупреждений о неверных ограничениях, ожидалось 4", warnings)
+	}
+}
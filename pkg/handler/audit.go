@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditHandler struct {
+	service service.AuditService
+	logger  *logrus.Logger
+}
+
+func NewAuditHandler(service service.AuditService, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{service: service, logger: logger}
+}
+
+// History godoc
+// @Summary История изменений подписки
+// @Description Записи журнала аудита о создании, изменении и удалении всех периодов подписки
+// @Description пользователя на сервис, начиная с последних
+// @Tags audit
+// @Produce json
+// @Param user_id path string true "ID пользователя"
+// @Param service_name path string true "Имя сервиса"
+// @Param actor_id query string false "ID пользователя или ключа API, выполнившего изменение"
+// @Param action query string false "Действие" Enums(create, update, delete, restore)
+// @Param from query string false "Начало периода (RFC 3339)"
+// @Param to query string false "Конец периода, не включая (RFC 3339)"
+// @Param limit query int false "Количество записей (1-500, по умолчанию 50)"
+// @Success 200 {object} model.AuditList
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{user_id}/{service_name}/history [get]
+func (h *AuditHandler) History(ctx *gin.Context) {
+	var req model.AuditRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(bindingError(err, apperr.CodeInvalidQueryParams, "Неверные параметры запроса"))
+		return
+	}
+
+	userID, serviceName, err := getUserIDAndServiceNameFromParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	history, err := h.service.History(ctx.Request.Context(), userID, serviceName, &req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, history)
+}
+
+// List godoc
+// @Summary Журнал аудита
+// @Description Записи журнала аудита об изменениях подписок всех пользователей, начиная с последних.
+// @Description Доступен ролям с действием audit в политике доступа и ключам API с областью audit:read
+// @Tags audit
+// @Produce json
+// @Param actor_id query string false "ID пользователя или ключа API, выполнившего изменение"
+// @Param user_id query string false "ID владельца подписки"
+// @Param service_name query string false "Имя сервиса"
+// @Param action query string false "Действие" Enums(create, update, delete, restore)
+// @Param from query string false "Начало периода (RFC 3339)"
+// @Param to query string false "Конец периода, не включая (RFC 3339)"
+// @Param limit query int false "Количество записей (1-500, по умолчанию 50)"
+// @Success 200 {object} model.AuditList
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /audit [get]
+func (h *AuditHandler) List(ctx *gin.Context) {
+	var req model.AuditRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(bindingError(err, apperr.CodeInvalidQueryParams, "Неверные параметры запроса"))
+		return
+	}
+
+	entries, err := h.service.List(ctx.Request.Context(), &req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+}
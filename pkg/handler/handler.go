@@ -1,23 +1,36 @@
 package handler
 
 import (
-	"database/sql"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/middleware"
 	"subscription_service/pkg/model"
 	"subscription_service/pkg/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
+// Тип содержимого JSON Merge Patch (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
 type Handler struct {
 	service service.Service
 	logger  *logrus.Logger
 }
 
 func NewSubHandler(service service.Service, logger *logrus.Logger) *Handler {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
 	return &Handler{service: service, logger: logger}
 }
 
@@ -28,67 +41,89 @@ func NewSubHandler(service service.Service, logger *logrus.Logger) *Handler {
 // @Accept json
 // @Produce json
 // @Param subscription body model.CreateSubscriptionRequest true "Subscription details"
-// @Success 201 {object} nil
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 409 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
+// @Success 201 {object} model.Subscription
+// @Header 201 {string} Location "Адрес созданной подписки"
+// @Header 201 {string} ETag "Версия подписки"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions [post]
 func (h *Handler) CreateSubscription(ctx *gin.Context) {
 	var req model.CreateSubscriptionRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Неверное тело запроса")
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверное тело запроса"})
-		return
-	}
-
-	err := h.service.CreateSubscription(ctx.Request.Context(), &req)
-	if err != nil {
-		switch err.Error() {
-		case "exists":
-			h.logger.Error("Ошибка добавления записи, запись уже существует")
-			ctx.JSON(http.StatusConflict, model.ErrorResponse{Error: "Подписка уже существует для данного пользователя"})
-			return
-		case "invalid startDate":
-			h.logger.WithError(err).Error("Неверный формат даты начала подписки")
-			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат даты начала подписки, ожидается формат MM-YYYY"})
-			return
-		case "invalid endDate":
-			h.logger.WithError(err).Error("Неверный формат даты окончания подписки")
-			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат даты окончания подписки, ожидается формат MM-YYYY"})
-			return
-		}
-		h.logger.WithError(err).Error("Ошибка добавления записи")
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		ctx.Error(bindingError(err, apperr.CodeInvalidRequestBody, "Неверное тело запроса"))
+		return
+	}
+
+	subscription, err := h.service.CreateSubscription(ctx.Request.Context(), &req)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	ctx.Status(http.StatusCreated)
+	ctx.Header("Location", "/subscriptions/by-id/"+subscription.ID.String())
+	ctx.Header("ETag", subscription.ETag())
+	ctx.JSON(http.StatusCreated, subscription)
 }
 
 // ListSubscriptions godoc
 // @Summary Список подписок
-// @Description Получить список подписок с возможностью фильтрации по пользователю и сервису
+// @Description Получить страницу списка подписок с фильтрацией и сортировкой.
+// @Description Для получения следующей страницы передайте значение next_cursor из ответа в параметре cursor вместе с той же сортировкой
 // @Tags subscriptions
 // @Produce json
 // @Param user_id query string false "User ID"
 // @Param service_name query string false "Service Name"
-// @Success 200 {array} model.Subscription
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Param service_prefix query string false "Начало названия сервиса, без учёта регистра"
+// @Param price_min query int false "Минимальная стоимость"
+// @Param price_max query int false "Максимальная стоимость"
+// @Param active_on query string false "Подписка действует в указанном месяце (MM-YYYY)"
+// @Param status query string false "Статус подписки на текущий месяц" Enums(active, ended)
+// @Param sort query string false "Поле сортировки, префикс - для сортировки по убыванию" Enums(start_date, -start_date, price, -price, service_name, -service_name)
+// @Param limit query int false "Размер страницы (1-500, по умолчанию 50)"
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param include_total query bool false "Вернуть общее количество подписок"
+// @Param include_deleted query bool false "Включить в список удалённые подписки"
+// @Success 200 {object} model.SubscriptionList
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions [get]
 func (h *Handler) ListSubscriptions(ctx *gin.Context) {
+	var req model.ListRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(bindingError(err, apperr.CodeInvalidQueryParams, "Неверные параметры запроса"))
+		return
+	}
 
 	userID, serviceName, err := getUserIDAndServiceNameFromQuery(ctx)
 	if err != nil {
-		h.logger.WithError(err).Error("Неверный формат UserID")
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат UserID"})
+		ctx.Error(err)
 		return
 	}
 
-	subscriptions, err := h.service.ListSubscriptions(ctx.Request.Context(), userID, serviceName)
+	req.ServiceName = serviceName
+	if req.ServicePrefix != nil && *req.ServicePrefix == "" {
+		req.ServicePrefix = nil
+	}
+
+	subscriptions, err := h.service.ListSubscriptions(ctx.Request.Context(), &req, userID)
 	if err != nil {
-		h.logger.WithError(err).Error("Ошибка получения списка подписок")
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -97,156 +132,545 @@ func (h *Handler) ListSubscriptions(ctx *gin.Context) {
 
 // GetSubscription godoc
 // @Summary Получить подписку
-// @Description Получить подписку по ID пользователя и имени сервиса
+// @Description Получить подписку по ID пользователя и имени сервиса.
+// @Description Если у пользователя несколько периодов подписки на сервис, возвращается последний из них
 // @Tags subscriptions
 // @Produce json
 // @Param user_id path string true "ID пользователя"
 // @Param service_name path string true "Имя сервиса"
+// @Param include_deleted query bool false "Учитывать удалённые периоды"
+// @Param If-None-Match header string false "ETag подписки, при совпадении возвращается 304"
 // @Success 200 {object} model.Subscription
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Header 200 {string} ETag "Версия подписки"
+// @Success 304 "Подписка не изменилась"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{user_id}/{service_name} [get]
 func (h *Handler) GetSubscription(ctx *gin.Context) {
 
 	userID, serviceName, err := getUserIDAndServiceNameFromParam(ctx)
 	if err != nil {
-		h.logger.WithError(err).Error("Неверный формат UserID")
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат UserID"})
+		ctx.Error(err)
 		return
 	}
 
-	subscription, err := h.service.GetSubscription(ctx.Request.Context(), userID, serviceName)
+	includeDeleted, err := getIncludeDeletedFromQuery(ctx)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			h.logger.WithError(err).Warn("Подписка не найдена")
-			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Подписка не найдена"})
-			return
-		}
-		h.logger.WithError(err).Error("Ошибка получения подписки")
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, subscription)
+	subscription, err := h.service.GetSubscription(ctx.Request.Context(), userID, serviceName, includeDeleted)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writeSubscriptionIfModified(ctx, subscription)
 }
 
 // UpdateSubscription godoc
-// @Summary Изменить подписку
-// @Description Изменение данных подписки по ID пользователя и имени сервиса
+// @Summary Заменить подписку
+// @Description Полная замена данных последнего периода подписки по ID пользователя и имени сервиса.
+// @Description ID пользователя и имя сервиса в теле запроса должны совпадать с указанными в пути
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param user_id path string true "ID пользователя"
 // @Param service_name path string true "Наименование сервиса"
-// @Param subscription body model.UpdateSubscriptionRequest true "Измененные данные подписки"
+// @Param subscription body model.CreateSubscriptionRequest true "Новые данные подписки"
+// @Param If-Match header string false "ETag подписки из ответа GET, обязателен при REQUIRE_IF_MATCH=true"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
 // @Success 200 {object} model.Subscription
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Header 200 {string} ETag "Версия подписки"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 412 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 428 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{user_id}/{service_name} [put]
 func (h *Handler) UpdateSubscription(ctx *gin.Context) {
 
 	userID, serviceName, err := getUserIDAndServiceNameFromParam(ctx)
 	if err != nil {
-		h.logger.WithError(err).Error("Неверный формат UserID")
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат UserID"})
+		ctx.Error(err)
 		return
 	}
 
-	var req model.UpdateSubscriptionRequest
+	var req model.CreateSubscriptionRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Неверное тело запроса")
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверное тело запроса"})
-		return
-	}
-
-	subscription, err := h.service.UpdateSubscription(ctx.Request.Context(), userID, serviceName, &req)
-	if err != nil {
-		switch err.Error() {
-		case "sql: no rows in result set":
-			h.logger.WithError(err).Warn("Подписка не найдена")
-			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Подписка не найдена"})
-			return
-		case "invalid startDate":
-			h.logger.WithError(err).Error("Неверный формат даты начала подписки")
-			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат даты начала подписки, ожидается формат MM-YYYY"})
-			return
-		case "invalid endDate":
-			h.logger.WithError(err).Error("Неверный формат даты окончания подписки")
-			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат даты окончания подписки, ожидается формат MM-YYYY"})
-			return
-		}
-		h.logger.WithError(err).Error("Ошибка обновления подписки")
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		ctx.Error(bindingError(err, apperr.CodeInvalidRequestBody, "Неверное тело запроса"))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, subscription)
+	if req.UserID != *userID || req.ServiceName != *serviceName {
+		ctx.Error(apperr.New(apperr.ErrValidation, apperr.CodePathMismatch, "ID пользователя и имя сервиса в теле запроса должны совпадать с указанными в пути"))
+		return
+	}
+
+	subscription, err := h.service.UpdateSubscription(ctx.Request.Context(), userID, serviceName, &req, model.ParseETags(ctx.GetHeader("If-Match")))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writeSubscription(ctx, subscription)
+}
+
+// PatchSubscription godoc
+// @Summary Изменить подписку
+// @Description Частичное изменение последнего периода подписки по ID пользователя и имени сервиса в формате JSON Merge Patch (RFC 7396).
+// @Description Отсутствующие поля не изменяются, "end_date": null очищает дату окончания подписки
+// @Tags subscriptions
+// @Accept application/merge-patch+json
+// @Accept json
+// @Produce json
+// @Param user_id path string true "ID пользователя"
+// @Param service_name path string true "Наименование сервиса"
+// @Param subscription body model.PatchSubscriptionRequest true "Измененные данные подписки"
+// @Param If-Match header string false "ETag подписки из ответа GET, обязателен при REQUIRE_IF_MATCH=true"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
+// @Success 200 {object} model.Subscription
+// @Header 200 {string} ETag "Версия подписки"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 412 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 415 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 428 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{user_id}/{service_name} [patch]
+func (h *Handler) PatchSubscription(ctx *gin.Context) {
+
+	userID, serviceName, err := getUserIDAndServiceNameFromParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	req, err := bindMergePatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	subscription, err := h.service.PatchSubscription(ctx.Request.Context(), userID, serviceName, req, model.ParseETags(ctx.GetHeader("If-Match")))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writeSubscription(ctx, subscription)
 }
 
 // DeleteSubscription godoc
 // @Summary Удалить подписку
-// @Description Удаление подписки по ID пользователя и имени сервиса
+// @Description Удаление последнего периода подписки по ID пользователя и имени сервиса.
+// @Description Подписка помечается удалённой и может быть восстановлена до окончательного удаления по истечении срока хранения
 // @Tags subscriptions
 // @Produce json
 // @Param user_id path string true "ID пользователя"
 // @Param service_name path string true "Наименование сервиса"
+// @Param If-Match header string false "ETag подписки из ответа GET, обязателен при REQUIRE_IF_MATCH=true"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
 // @Success 200 {object} nil
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 412 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 428 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{user_id}/{service_name} [delete]
 func (h *Handler) DeleteSubscription(ctx *gin.Context) {
 
 	userID, serviceName, err := getUserIDAndServiceNameFromParam(ctx)
 
 	if err != nil {
-		h.logger.WithError(err).Error("Неверный формат UserID")
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат UserID"})
+		ctx.Error(err)
 		return
 	}
 
-	if err := h.service.DeleteSubscription(ctx.Request.Context(), userID, serviceName); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			h.logger.WithError(err).Warn("Подписка не найдена")
-			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Подписка не найдена"})
-			return
-		}
-		h.logger.WithError(err).Error("Ошибка удаления подписки")
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	if err := h.service.DeleteSubscription(ctx.Request.Context(), userID, serviceName, model.ParseETags(ctx.GetHeader("If-Match"))); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// RestoreSubscription godoc
+// @Summary Восстановить подписку
+// @Description Восстановление последнего удалённого периода подписки по ID пользователя и имени сервиса
+// @Tags subscriptions
+// @Produce json
+// @Param user_id path string true "ID пользователя"
+// @Param service_name path string true "Наименование сервиса"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
+// @Success 200 {object} model.Subscription
+// @Header 200 {string} ETag "Версия подписки"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{user_id}/{service_name}/restore [post]
+func (h *Handler) RestoreSubscription(ctx *gin.Context) {
+
+	userID, serviceName, err := getUserIDAndServiceNameFromParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	subscription, err := h.service.RestoreSubscription(ctx.Request.Context(), userID, serviceName)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writeSubscription(ctx, subscription)
+}
+
+// GetSubscriptionByID godoc
+// @Summary Получить подписку по ID
+// @Description Получить подписку по её идентификатору
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param include_deleted query bool false "Возвращать удалённую подписку"
+// @Param If-None-Match header string false "ETag подписки, при совпадении возвращается 304"
+// @Success 200 {object} model.Subscription
+// @Header 200 {string} ETag "Версия подписки"
+// @Success 304 "Подписка не изменилась"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/by-id/{id} [get]
+func (h *Handler) GetSubscriptionByID(ctx *gin.Context) {
+
+	id, err := getIDFromParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	includeDeleted, err := getIncludeDeletedFromQuery(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	subscription, err := h.service.GetSubscriptionByID(ctx.Request.Context(), id, includeDeleted)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writeSubscriptionIfModified(ctx, subscription)
+}
+
+// UpdateSubscriptionByID godoc
+// @Summary Заменить подписку по ID
+// @Description Полная замена данных подписки по её идентификатору
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param subscription body model.CreateSubscriptionRequest true "Новые данные подписки"
+// @Param If-Match header string false "ETag подписки из ответа GET, обязателен при REQUIRE_IF_MATCH=true"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
+// @Success 200 {object} model.Subscription
+// @Header 200 {string} ETag "Версия подписки"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 412 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 428 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/by-id/{id} [put]
+func (h *Handler) UpdateSubscriptionByID(ctx *gin.Context) {
+
+	id, err := getIDFromParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var req model.CreateSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(bindingError(err, apperr.CodeInvalidRequestBody, "Неверное тело запроса"))
+		return
+	}
+
+	subscription, err := h.service.UpdateSubscriptionByID(ctx.Request.Context(), id, &req, model.ParseETags(ctx.GetHeader("If-Match")))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writeSubscription(ctx, subscription)
+}
+
+// PatchSubscriptionByID godoc
+// @Summary Изменить подписку по ID
+// @Description Частичное изменение подписки по её идентификатору в формате JSON Merge Patch (RFC 7396).
+// @Description Отсутствующие поля не изменяются, "end_date": null очищает дату окончания подписки
+// @Tags subscriptions
+// @Accept application/merge-patch+json
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param subscription body model.PatchSubscriptionRequest true "Измененные данные подписки"
+// @Param If-Match header string false "ETag подписки из ответа GET, обязателен при REQUIRE_IF_MATCH=true"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
+// @Success 200 {object} model.Subscription
+// @Header 200 {string} ETag "Версия подписки"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 412 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 415 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 428 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/by-id/{id} [patch]
+func (h *Handler) PatchSubscriptionByID(ctx *gin.Context) {
+
+	id, err := getIDFromParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	req, err := bindMergePatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	subscription, err := h.service.PatchSubscriptionByID(ctx.Request.Context(), id, req, model.ParseETags(ctx.GetHeader("If-Match")))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writeSubscription(ctx, subscription)
+}
+
+// DeleteSubscriptionByID godoc
+// @Summary Удалить подписку по ID
+// @Description Удаление подписки по её идентификатору.
+// @Description Подписка помечается удалённой и может быть восстановлена до окончательного удаления по истечении срока хранения
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param If-Match header string false "ETag подписки из ответа GET, обязателен при REQUIRE_IF_MATCH=true"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
+// @Success 200 {object} nil
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 412 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 428 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/by-id/{id} [delete]
+func (h *Handler) DeleteSubscriptionByID(ctx *gin.Context) {
+
+	id, err := getIDFromParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := h.service.DeleteSubscriptionByID(ctx.Request.Context(), id, model.ParseETags(ctx.GetHeader("If-Match"))); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	ctx.Status(http.StatusOK)
 }
 
+// RestoreSubscriptionByID godoc
+// @Summary Восстановить подписку по ID
+// @Description Восстановление удалённой подписки по её идентификатору
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
+// @Success 200 {object} model.Subscription
+// @Header 200 {string} ETag "Версия подписки"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/by-id/{id}/restore [post]
+func (h *Handler) RestoreSubscriptionByID(ctx *gin.Context) {
+
+	id, err := getIDFromParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	subscription, err := h.service.RestoreSubscriptionByID(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writeSubscription(ctx, subscription)
+}
+
+// ImportSubscriptions godoc
+// @Summary Импортировать подписки
+// @Description Загружает подписки из файла CSV (text/csv, первая строка - названия столбцов service_name, price, user_id, start_date, end_date)
+// @Description или NDJSON (application/x-ndjson, по объекту CreateSubscriptionRequest в строке) в одной транзакции и возвращает результат по каждой строке.
+// @Description Режимы: all_or_nothing - при любой ошибке ничего не сохраняется и возвращается код 422, skip_invalid - ошибочные строки пропускаются,
+// @Description upsert - как skip_invalid, но строка с датой начала существующего периода подписки обновляет его стоимость и дату окончания
+// @Tags subscriptions
+// @Accept text/csv,application/x-ndjson
+// @Produce json
+// @Param mode query string false "Режим импорта (по умолчанию all_or_nothing)" Enums(all_or_nothing, skip_invalid, upsert)
+// @Param file body string true "Файл с подписками"
+// @Param Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с ним возвращает сохранённый ответ"
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 413 {object} model.Problem
+// @Failure 415 {object} model.Problem
+// @Failure 422 {object} model.ImportReport
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/import [post]
+func (h *Handler) ImportSubscriptions(ctx *gin.Context) {
+	var req model.ImportRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(bindingError(err, apperr.CodeInvalidQueryParams, "Неверные параметры запроса"))
+		return
+	}
+
+	report, err := h.service.ImportSubscriptions(ctx.Request.Context(), importFormat(ctx.ContentType()), ctx.Request.Body, req.Mode)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.Code != "" {
+			row.Message, row.Errors = middleware.LocalizeError(ctx, row.Code, row.Message, row.Errors)
+		}
+	}
+
+	status := http.StatusOK
+	if !report.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+	ctx.JSON(status, report)
+}
+
 // GetTotalSubscriptions godoc
 // @Summary Получить общую стоимость подписок
-// @Description Получите общую стоимость и количество подписок за определенный период с дополнительной фильтрацией
+// @Description Получите общую стоимость и количество подписок за определенный период с дополнительной фильтрацией.
+// @Description Стоимость каждой подписки умножается на количество месяцев, в течение которых она действовала внутри периода
 // @Tags subscriptions
 // @Produce json
 // @Param user_id query string false "ID пользователя"
 // @Param service_name query string false "Наименование сервиса"
 // @Param start_period query string true "Начало периода (MM-YYYY)"
 // @Param end_period query string true "Конец периода (MM-YYYY)"
+// @Param include_deleted query bool false "Учитывать удалённые подписки"
 // @Success 200 {object} model.TotalResponse
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/total [get]
 func (h *Handler) GetTotalSubscriptions(ctx *gin.Context) {
 	var req model.TotalRequest
 
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		h.logger.WithError(err).Error("Неверные параметры запроса")
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверные параметры запроса"})
+		ctx.Error(bindingError(err, apperr.CodeInvalidQueryParams, "Неверные параметры запроса"))
 		return
 	}
 
 	userID, serviceName, err := getUserIDAndServiceNameFromQuery(ctx)
 	if err != nil {
-		h.logger.WithError(err).Error("Неверный формат UserID")
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат UserID"})
+		ctx.Error(err)
 		return
 	}
 
@@ -254,23 +678,156 @@ func (h *Handler) GetTotalSubscriptions(ctx *gin.Context) {
 
 	total, err := h.service.GetTotal(ctx.Request.Context(), &req, userID)
 	if err != nil {
-		switch err.Error() {
-		case "invalid startDate":
-			h.logger.WithError(err).Error("Неверный формат даты начала периода")
-			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат даты начала периода, ожидается формат MM-YYYY"})
-			return
-		case "invalid endDate":
-			h.logger.WithError(err).Error("Неверный формат даты окончания периода")
-			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Неверный формат даты окончания периода, ожидается формат MM-YYYY"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, total)
 }
 
+// GetTotalBreakdown godoc
+// @Summary Детализация расходов на подписки
+// @Description Возвращает расходы на подписки за период, сгруппированные по месяцам, сервисам и/или пользователям.
+// @Description Группировки задаются параметром group_by (month, service_name, user_id) и могут комбинироваться, по умолчанию month
+// @Tags subscriptions
+// @Produce json
+// @Param user_id query string false "ID пользователя"
+// @Param service_name query string false "Наименование сервиса"
+// @Param start_period query string true "Начало периода (MM-YYYY)"
+// @Param end_period query string true "Конец периода (MM-YYYY)"
+// @Param include_deleted query bool false "Учитывать удалённые подписки"
+// @Param group_by query []string false "Группировка (month, service_name, user_id)" collectionFormat(csv)
+// @Success 200 {array} model.BreakdownItem
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 422 {object} model.Problem
+// @Failure 429 {object} model.Problem
+// @Failure 500 {object} model.Problem
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/total/breakdown [get]
+func (h *Handler) GetTotalBreakdown(ctx *gin.Context) {
+	var req model.BreakdownRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(bindingError(err, apperr.CodeInvalidQueryParams, "Неверные параметры запроса"))
+		return
+	}
+
+	userID, serviceName, err := getUserIDAndServiceNameFromQuery(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	req.ServiceName = serviceName
+
+	breakdown, err := h.service.GetTotalBreakdown(ctx.Request.Context(), &req, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, breakdown)
+}
+
+// bindingError переводит ошибку разбора тела или параметров запроса в ошибку валидации
+// со списком всех неверных полей
+func bindingError(err error, code, message string) error {
+	validationErr := apperr.Wrap(apperr.ErrValidation, code, message, err)
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fieldErr := range validationErrs {
+			validationErr.AddParam(fieldErr.Field(), validationCode(fieldErr), fieldErr.Param(), validationMessage(fieldErr))
+		}
+	case errors.As(err, &typeErr):
+		validationErr.Add(typeErr.Field, apperr.FieldInvalidType, "Неверный тип значения")
+	}
+
+	return validationErr
+}
+
+// importFormat определяет формат файла импорта по типу содержимого запроса
+func importFormat(contentType string) string {
+	switch contentType {
+	case "text/csv":
+		return model.ImportFormatCSV
+	case "application/x-ndjson", "application/ndjson":
+		return model.ImportFormatNDJSON
+	}
+	return ""
+}
+
+// bindMergePatch читает тело запроса в формате JSON Merge Patch
+func bindMergePatch(ctx *gin.Context) (*model.PatchSubscriptionRequest, error) {
+	if contentType := ctx.ContentType(); contentType != mergePatchContentType && contentType != gin.MIMEJSON {
+		return nil, apperr.New(apperr.ErrUnsupportedMediaType, apperr.CodeUnsupportedMediaType, "Ожидается тело запроса в формате application/merge-patch+json")
+	}
+
+	var req model.PatchSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return nil, bindingError(err, apperr.CodeInvalidRequestBody, "Неверное тело запроса")
+	}
+
+	return &req, nil
+}
+
+func validationCode(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return apperr.FieldRequired
+	case "min":
+		return apperr.FieldTooSmall
+	case "max":
+		return apperr.FieldTooLarge
+	case "oneof":
+		return apperr.FieldNotAllowed
+	}
+	return apperr.FieldInvalidValue
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "Обязательное поле"
+	case "min":
+		return "Значение должно быть не меньше " + fieldErr.Param()
+	case "max":
+		return "Значение должно быть не больше " + fieldErr.Param()
+	case "oneof":
+		return "Допустимые значения: " + fieldErr.Param()
+	}
+	return "Неверное значение"
+}
+
+// fieldName возвращает имя поля из тега json или form, чтобы ошибки валидации
+// ссылались на поля в том виде, в котором они передаются в запросе
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func getIDFromParam(ctx *gin.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return uuid.Nil, apperr.Wrap(apperr.ErrValidation, apperr.CodeInvalidSubscriptionID, "Неверный формат ID подписки", err).
+			Add("id", apperr.FieldInvalidUUID, "Ожидается UUID")
+	}
+	return id, nil
+}
+
 func getUserIDAndServiceNameFromParam(ctx *gin.Context) (*uuid.UUID, *string, error) {
 	var userID *uuid.UUID
 	var serviceName *string
@@ -278,7 +835,8 @@ func getUserIDAndServiceNameFromParam(ctx *gin.Context) (*uuid.UUID, *string, er
 	if userIDStr := ctx.Param("user_id"); userIDStr != "" {
 		parsedUUID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, apperr.Wrap(apperr.ErrValidation, apperr.CodeInvalidUserID, "Неверный формат UserID", err).
+				Add("user_id", apperr.FieldInvalidUUID, "Ожидается UUID")
 		}
 		userID = &parsedUUID
 	}
@@ -296,7 +854,8 @@ func getUserIDAndServiceNameFromQuery(ctx *gin.Context) (*uuid.UUID, *string, er
 	if userIDStr := ctx.Query("user_id"); userIDStr != "" {
 		parsedUUID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, apperr.Wrap(apperr.ErrValidation, apperr.CodeInvalidUserID, "Неверный формат UserID", err).
+				Add("user_id", apperr.FieldInvalidUUID, "Ожидается UUID")
 		}
 		userID = &parsedUUID
 	}
@@ -307,3 +866,34 @@ func getUserIDAndServiceNameFromQuery(ctx *gin.Context) (*uuid.UUID, *string, er
 
 	return userID, serviceName, nil
 }
+
+// writeSubscription отвечает подпиской с её версией в заголовке ETag
+func writeSubscription(ctx *gin.Context, subscription *model.Subscription) {
+	ctx.Header("ETag", subscription.ETag())
+	ctx.JSON(http.StatusOK, subscription)
+}
+
+// writeSubscriptionIfModified отвечает кодом 304 без тела, если клиент передал
+// текущую версию подписки в If-None-Match, иначе возвращает подписку
+func writeSubscriptionIfModified(ctx *gin.Context, subscription *model.Subscription) {
+	if model.MatchETag(model.ParseETags(ctx.GetHeader("If-None-Match")), subscription.ETag(), true) {
+		ctx.Header("ETag", subscription.ETag())
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	writeSubscription(ctx, subscription)
+}
+
+func getIncludeDeletedFromQuery(ctx *gin.Context) (bool, error) {
+	value := ctx.Query("include_deleted")
+	if value == "" {
+		return false, nil
+	}
+
+	includeDeleted, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, apperr.Wrap(apperr.ErrValidation, apperr.CodeInvalidQueryParams, "Неверные параметры запроса", err).
+			Add("include_deleted", apperr.FieldInvalidType, "Неверный тип значения")
+	}
+	return includeDeleted, nil
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/service"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type HealthHandler struct {
+	service service.HealthService
+	// Сервер принимает запросы: false до завершения запуска и во время остановки
+	ready  atomic.Bool
+	logger *logrus.Logger
+}
+
+func NewHealthHandler(service service.HealthService, logger *logrus.Logger) *HealthHandler {
+	return &HealthHandler{service: service, logger: logger}
+}
+
+// SetReady отмечает, готов ли сервер принимать запросы
+func (h *HealthHandler) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Liveness godoc
+// @Summary Проверка работоспособности
+// @Description Сервис запущен и отвечает на запросы
+// @Tags health
+// @Produce json
+// @Success 200 {object} model.HealthReport
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, model.HealthReport{Status: model.HealthStatusUp})
+}
+
+// Readiness godoc
+// @Summary Проверка готовности
+// @Description Проверяет подключение к БД и версию миграций.
+// @Description Во время запуска и остановки сервиса возвращает 503
+// @Tags health
+// @Produce json
+// @Success 200 {object} model.HealthReport
+// @Failure 503 {object} model.HealthReport
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(ctx *gin.Context) {
+	if !h.ready.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, model.HealthReport{Status: model.HealthStatusDown})
+		return
+	}
+
+	report := h.service.Check(ctx.Request.Context())
+	if report.Status != model.HealthStatusUp {
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
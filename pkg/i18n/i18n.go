@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемые языки сообщений API
+const (
+	Russian = "ru"
+	English = "en"
+)
+
+// Supported сообщает, есть ли каталог сообщений для языка lang
+func Supported(lang string) bool {
+	_, ok := catalog[lang]
+	return ok
+}
+
+// Message возвращает сообщение с ключом key на языке lang.
+// Подстановка {param} в сообщении заменяется значением param
+func Message(lang, key, param string) (string, bool) {
+	message, ok := catalog[lang][key]
+	if !ok {
+		return "", false
+	}
+	return strings.ReplaceAll(message, "{param}", param), true
+}
+
+// Translate переводит сообщение message с ключом key на язык lang.
+// Переводится только исходный текст каталога (на русском языке) с подставленным param
+// или пустое сообщение. Сообщение с подробностями, которых нет в каталоге, возвращается без изменений
+func Translate(lang, key, param, message string) string {
+	source, ok := Message(Russian, key, param)
+	if !ok || (message != "" && message != source) {
+		return message
+	}
+	if translated, ok := Message(lang, key, param); ok {
+		return translated
+	}
+	return message
+}
+
+// StatusTitle возвращает краткое описание кода HTTP-ответа на языке lang
+func StatusTitle(lang string, status int) (string, bool) {
+	return Message(lang, "STATUS_"+strconv.Itoa(status), "")
+}
+
+// ParseAcceptLanguage выбирает из заголовка Accept-Language поддерживаемый язык с наибольшим весом.
+// Если ни один язык не поддерживается, возвращается fallback
+func ParseAcceptLanguage(header, fallback string) string {
+	type candidate struct {
+		lang   string
+		weight float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		// Учитывается только основной тег языка: en-US -> en
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		candidates = append(candidates, candidate{lang: primary, weight: weight})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].weight > candidates[j].weight
+	})
+
+	for _, c := range candidates {
+		if c.lang == "*" {
+			return fallback
+		}
+		if Supported(c.lang) {
+			return c.lang
+		}
+	}
+
+	return fallback
+}
@@ -0,0 +1,78 @@
+package i18n
+
+import "testing"
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		fallback string
+		want     string
+	}{
+		{"пустой заголовок", "", Russian, Russian},
+		{"пустой заголовок, английский по умолчанию", "", English, English},
+		{"английский", "en", Russian, English},
+		{"региональный вариант", "en-US", Russian, English},
+		{"регистр не учитывается", "EN-gb", Russian, English},
+		{"наибольший вес", "ru;q=0.5, en;q=0.9", Russian, English},
+		{"порядок при равном весе", "ru, en", English, Russian},
+		{"неподдерживаемый язык пропускается", "de, en;q=0.1", Russian, English},
+		{"нет поддерживаемых языков", "de, fr", English, English},
+		{"любой язык", "*", English, English},
+		{"нулевой вес исключает язык", "en;q=0", Russian, Russian},
+		{"неверный вес пропускается", "en;q=abc, ru;q=0.1", English, Russian},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tt.header, tt.fallback); got != tt.want {
+				t.Errorf("ParseAcceptLanguage(%q, %q) = %q, ожидалось %q", tt.header, tt.fallback, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name    string
+		lang    string
+		key     string
+		param   string
+		message string
+		want    string
+	}{
+		{"исходный текст на русском", Russian, "SUBSCRIPTION_NOT_FOUND", "", "Подписка не найдена", "Подписка не найдена"},
+		{"исходный текст на английском", English, "SUBSCRIPTION_NOT_FOUND", "", "Подписка не найдена", "Subscription not found"},
+		{"пустое сообщение", English, "SUBSCRIPTION_NOT_FOUND", "", "", "Subscription not found"},
+		{"подстановка параметра", English, "TOO_SMALL", "1", "Значение должно быть не меньше 1", "Value must be at least 1"},
+		{"параметр в сообщении ошибки", English, "VERSION_MISMATCH", "3", "Подписка была изменена, текущая версия 3", "The subscription has been modified, the current version is 3"},
+		{"сообщение с подробностями", English, "INVALID_REQUEST_BODY", "", "Неверная строка CSV", "Неверная строка CSV"},
+		{"неизвестный ключ", English, "UNKNOWN_CODE", "", "Сообщение", "Сообщение"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Translate(tt.lang, tt.key, tt.param, tt.message); got != tt.want {
+				t.Errorf("Translate(%q, %q) = %q, ожидалось %q", tt.lang, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+// Каждое сообщение должно быть переведено на все поддерживаемые языки
+func TestCatalogComplete(t *testing.T) {
+	for key := range catalog[Russian] {
+		for lang, messages := range catalog {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("нет перевода %q на язык %q", key, lang)
+			}
+		}
+	}
+	for lang, messages := range catalog {
+		for key := range messages {
+			if _, ok := catalog[Russian][key]; !ok {
+				t.Errorf("нет исходного текста %q для перевода на язык %q", key, lang)
+			}
+		}
+	}
+}
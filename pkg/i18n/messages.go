@@ -0,0 +1,132 @@
+package i18n
+
+// catalog - сообщения API по языкам, ключом служит код ошибки из пакета apperr
+// или STATUS_<код> для описания кода HTTP-ответа
+var catalog = map[string]map[string]string{
+	Russian: {
+		"STATUS_400": "Неверный запрос",
+		"STATUS_401": "Требуется аутентификация",
+		"STATUS_403": "Доступ запрещён",
+		"STATUS_404": "Не найдено",
+		"STATUS_409": "Конфликт",
+		"STATUS_412": "Условие запроса не выполнено",
+		"STATUS_413": "Слишком большой запрос",
+		"STATUS_415": "Неподдерживаемый тип содержимого",
+		"STATUS_422": "Необрабатываемые данные",
+		"STATUS_428": "Требуется условие запроса",
+		"STATUS_429": "Слишком много запросов",
+		"STATUS_500": "Внутренняя ошибка сервера",
+
+		"INTERNAL_ERROR":              "Внутренняя ошибка сервера",
+		"ROUTE_NOT_FOUND":             "Маршрут не найден",
+		"SUBSCRIPTION_NOT_FOUND":      "Подписка не найдена",
+		"SUBSCRIPTION_EXISTS":         "Период подписки пересекается с существующим периодом этой подписки",
+		"SUBSCRIPTION_NOT_DELETED":    "Подписка не удалена",
+		"INVALID_REQUEST_BODY":        "Неверное тело запроса",
+		"INVALID_QUERY_PARAMS":        "Неверные параметры запроса",
+		"INVALID_USER_ID":             "Неверный формат UserID",
+		"INVALID_SUBSCRIPTION_ID":     "Неверный формат ID подписки",
+		"INVALID_DATE":                "Неверный формат даты",
+		"INVALID_PERIOD":              "Дата окончания не может быть раньше даты начала",
+		"INVALID_PRICE_RANGE":         "Неверный диапазон цен",
+		"VALIDATION_FAILED":           "Данные подписки не прошли проверку",
+		"PATH_MISMATCH":               "ID пользователя и имя сервиса в теле запроса должны совпадать с указанными в пути",
+		"CONSTRAINT_VIOLATION":        "Данные подписки нарушают ограничения хранилища",
+		"UNSUPPORTED_MEDIA_TYPE":      "Ожидается тело запроса в формате application/merge-patch+json",
+		"UNAUTHORIZED":                "Требуется токен доступа в заголовке Authorization",
+		"INVALID_TOKEN":               "Токен доступа недействителен",
+		"ACCESS_DENIED":               "Нет доступа к подпискам другого пользователя",
+		"ACTION_NOT_PERMITTED":        "Действие не разрешено ролям пользователя",
+		"INVALID_API_KEY":             "Ключ API недействителен или отозван",
+		"API_KEY_REQUIRED":            "Требуется ключ API в заголовке X-API-Key",
+		"API_KEY_NOT_FOUND":           "Ключ API не найден",
+		"INSUFFICIENT_SCOPE":          "Ключу API не разрешена эта операция",
+		"RATE_LIMIT_EXCEEDED":         "Слишком много запросов, повторите позже",
+		"VERSION_MISMATCH":            "Подписка была изменена, текущая версия {param}",
+		"IF_MATCH_REQUIRED":           "Требуется заголовок If-Match с ETag подписки",
+		"INVALID_IDEMPOTENCY_KEY":     "Заголовок Idempotency-Key должен содержать от 1 до 255 символов",
+		"REQUEST_TOO_LARGE":           "Тело запроса с заголовком Idempotency-Key не должно превышать {param} байт",
+		"IDEMPOTENCY_KEY_REUSED":      "Ключ идемпотентности уже использован для другого запроса",
+		"IDEMPOTENCY_KEY_IN_PROGRESS": "Запрос с этим ключом идемпотентности ещё выполняется, повторите позже",
+		"INVALID_IMPORT_FILE":         "Неверный файл импорта",
+		"UNSUPPORTED_IMPORT_FORMAT":   "Ожидается файл в формате text/csv или application/x-ndjson",
+
+		"REQUIRED":                 "Обязательное поле",
+		"TOO_SMALL":                "Значение должно быть не меньше {param}",
+		"TOO_LARGE":                "Значение должно быть не больше {param}",
+		"INVALID_TYPE":             "Неверный тип значения",
+		"INVALID_VALUE":            "Неверное значение",
+		"INVALID_UUID":             "Ожидается UUID",
+		"INVALID_DATE_FORMAT":      "Неверный формат даты, ожидается формат MM-YYYY",
+		"INVALID_TIMESTAMP_FORMAT": "Неверный формат времени, ожидается RFC 3339",
+		"END_BEFORE_START":         "Дата окончания не может быть раньше даты начала",
+		"NOT_ALLOWED":              "Допустимые значения: {param}",
+		"INVALID_CURSOR":           "Курсор повреждён или получен для другой сортировки",
+		"MAX_LESS_THAN_MIN":        "Максимальное значение не может быть меньше минимального",
+		"SCOPE_REQUIRED":           "Требуется область доступа {param}",
+		"OVERLAPS_LINE":            "Период пересекается с периодом той же подписки в строке {param}",
+	},
+	English: {
+		"STATUS_400": "Bad Request",
+		"STATUS_401": "Unauthorized",
+		"STATUS_403": "Forbidden",
+		"STATUS_404": "Not Found",
+		"STATUS_409": "Conflict",
+		"STATUS_412": "Precondition Failed",
+		"STATUS_413": "Content Too Large",
+		"STATUS_415": "Unsupported Media Type",
+		"STATUS_422": "Unprocessable Entity",
+		"STATUS_428": "Precondition Required",
+		"STATUS_429": "Too Many Requests",
+		"STATUS_500": "Internal Server Error",
+
+		"INTERNAL_ERROR":              "Internal server error",
+		"ROUTE_NOT_FOUND":             "Route not found",
+		"SUBSCRIPTION_NOT_FOUND":      "Subscription not found",
+		"SUBSCRIPTION_EXISTS":         "The subscription period overlaps an existing period of this subscription",
+		"SUBSCRIPTION_NOT_DELETED":    "The subscription is not deleted",
+		"INVALID_REQUEST_BODY":        "Invalid request body",
+		"INVALID_QUERY_PARAMS":        "Invalid query parameters",
+		"INVALID_USER_ID":             "Invalid user ID format",
+		"INVALID_SUBSCRIPTION_ID":     "Invalid subscription ID format",
+		"INVALID_DATE":                "Invalid date format",
+		"INVALID_PERIOD":              "The end date cannot be earlier than the start date",
+		"INVALID_PRICE_RANGE":         "Invalid price range",
+		"VALIDATION_FAILED":           "Subscription data failed validation",
+		"PATH_MISMATCH":               "User ID and service name in the request body must match the path",
+		"CONSTRAINT_VIOLATION":        "Subscription data violates storage constraints",
+		"UNSUPPORTED_MEDIA_TYPE":      "Request body must be application/merge-patch+json",
+		"UNAUTHORIZED":                "An access token is required in the Authorization header",
+		"INVALID_TOKEN":               "The access token is invalid",
+		"ACCESS_DENIED":               "Access to another user's subscriptions is denied",
+		"ACTION_NOT_PERMITTED":        "The action is not permitted for the user's roles",
+		"INVALID_API_KEY":             "The API key is invalid or revoked",
+		"API_KEY_REQUIRED":            "An API key is required in the X-API-Key header",
+		"API_KEY_NOT_FOUND":           "API key not found",
+		"INSUFFICIENT_SCOPE":          "The API key is not allowed to perform this operation",
+		"RATE_LIMIT_EXCEEDED":         "Too many requests, try again later",
+		"VERSION_MISMATCH":            "The subscription has been modified, the current version is {param}",
+		"IF_MATCH_REQUIRED":           "The If-Match header with the subscription ETag is required",
+		"INVALID_IDEMPOTENCY_KEY":     "The Idempotency-Key header must contain from 1 to 255 characters",
+		"REQUEST_TOO_LARGE":           "The body of a request with the Idempotency-Key header must not exceed {param} bytes",
+		"IDEMPOTENCY_KEY_REUSED":      "The idempotency key has already been used for a different request",
+		"IDEMPOTENCY_KEY_IN_PROGRESS": "A request with this idempotency key is still in progress, try again later",
+		"INVALID_IMPORT_FILE":         "Invalid import file",
+		"UNSUPPORTED_IMPORT_FORMAT":   "The import file must be text/csv or application/x-ndjson",
+
+		"REQUIRED":                 "This field is required",
+		"TOO_SMALL":                "Value must be at least {param}",
+		"TOO_LARGE":                "Value must be at most {param}",
+		"INVALID_TYPE":             "Invalid value type",
+		"INVALID_VALUE":            "Invalid value",
+		"INVALID_UUID":             "A UUID is expected",
+		"INVALID_DATE_FORMAT":      "Invalid date format, expected MM-YYYY",
+		"INVALID_TIMESTAMP_FORMAT": "Invalid time format, expected RFC 3339",
+		"END_BEFORE_START":         "The end date cannot be earlier than the start date",
+		"NOT_ALLOWED":              "Allowed values: {param}",
+		"INVALID_CURSOR":           "The cursor is corrupted or was issued for a different sort order",
+		"MAX_LESS_THAN_MIN":        "The maximum cannot be less than the minimum",
+		"SCOPE_REQUIRED":           "The {param} scope is required",
+		"OVERLAPS_LINE":            "The period overlaps the period of the same subscription on line {param}",
+	},
+}
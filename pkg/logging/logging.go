@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type entryKey struct{}
+
+type requestIDKey struct{}
+
+// WithEntry сохраняет в контексте запись логгера с полями запроса
+func WithEntry(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// WithFields добавляет поля к записи логгера из контекста
+func WithFields(ctx context.Context, logger *logrus.Logger, fields logrus.Fields) context.Context {
+	return WithEntry(ctx, FromContext(ctx, logger).WithFields(fields))
+}
+
+// FromContext возвращает запись логгера из контекста запроса.
+// Вне запроса (фоновые задачи, запуск сервиса) используется logger без дополнительных полей
+func FromContext(ctx context.Context, logger *logrus.Logger) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(logger)
+}
+
+// WithRequestID сохраняет в контексте идентификатор запроса
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID возвращает идентификатор запроса из контекста или пустую строку вне запроса
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestFromContext(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	// Вне запроса используется логгер без дополнительных полей
+	FromContext(context.Background(), logger).Info("запуск")
+	if fields := hook.LastEntry().Data; len(fields) != 0 {
+		t.Errorf("поля вне запроса %v, ожидалось отсутствие полей", fields)
+	}
+
+	ctx := WithFields(context.Background(), logger, logrus.Fields{"request_id": "req-1"})
+	ctx = WithFields(ctx, logger, logrus.Fields{"user_id": "user-1"})
+	FromContext(ctx, logger).Info("запрос")
+
+	entry := hook.LastEntry()
+	if entry.Data["request_id"] != "req-1" || entry.Data["user_id"] != "user-1" {
+		t.Errorf("поля записи %v, ожидались request_id и user_id", entry.Data)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if got := RequestID(context.Background()); got != "" {
+		t.Errorf("RequestID() вне запроса = %q", got)
+	}
+	if got := RequestID(WithRequestID(context.Background(), "req-1")); got != "req-1" {
+		t.Errorf("RequestID() = %q, ожидался req-1", got)
+	}
+}
@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"subscription_service/pkg/config"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Output - приёмники логов: stdout и/или файл с ротацией
+type Output struct {
+	file *lumberjack.Logger
+	stop chan struct{}
+	once sync.Once
+}
+
+// Setup настраивает уровень, формат и приёмники логов logger по конфигурации
+func Setup(logger *logrus.Logger, cfg config.LogConfig) (*Output, error) {
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+	logger.SetLevel(level)
+
+	switch cfg.Format {
+	case config.LogFormatText:
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	output := &Output{stop: make(chan struct{})}
+
+	var writers []io.Writer
+	if cfg.Output == config.LogOutputStdout || cfg.Output == config.LogOutputBoth {
+		writers = append(writers, os.Stdout)
+	}
+	if cfg.Output == config.LogOutputFile || cfg.Output == config.LogOutputBoth {
+		// lumberjack открывает файл только при первой записи, поэтому недоступный путь
+		// проверяется заранее, пока логгер ещё пишет в stderr
+		if err := checkWritable(cfg.File); err != nil {
+			return nil, err
+		}
+		output.file = &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxAge:     cfg.MaxAgeDays,
+			MaxBackups: cfg.MaxBackups,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		}
+		writers = append(writers, output.file)
+	}
+	if len(writers) == 0 {
+		return nil, fmt.Errorf("unknown log output %q", cfg.Output)
+	}
+	logger.SetOutput(io.MultiWriter(writers...))
+
+	if output.file != nil && cfg.RotateInterval > 0 {
+		go output.rotateEvery(logger, cfg.RotateInterval)
+	}
+
+	return output, nil
+}
+
+// checkWritable создаёт каталог и файл логов так же, как lumberjack, и проверяет, что в файл можно писать
+func checkWritable(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	return file.Close()
+}
+
+// rotateEvery начинает новый файл логов раз в interval независимо от его размера
+func (o *Output) rotateEvery(logger *logrus.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := o.file.Rotate(); err != nil {
+				logger.WithError(err).Error("Ошибка ротации файла логов")
+			}
+		case <-o.stop:
+			return
+		}
+	}
+}
+
+// Reopen закрывает файл логов, следующая запись откроет его заново по исходному пути.
+// Используется после переименования файла внешним logrotate (по сигналу SIGHUP)
+func (o *Output) Reopen() error {
+	if o.file == nil {
+		return nil
+	}
+	return o.file.Close()
+}
+
+// Close останавливает ротацию по времени и закрывает файл логов
+func (o *Output) Close() error {
+	o.once.Do(func() { close(o.stop) })
+	if o.file == nil {
+		return nil
+	}
+	return o.file.Close()
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"subscription_service/pkg/config"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.LogConfig
+		wantLevel logrus.Level
+		wantText  bool
+	}{
+		{"json и уровень debug", config.LogConfig{Level: "debug", Output: config.LogOutputStdout, Format: config.LogFormatJSON},
+			logrus.DebugLevel, false},
+		{"текстовый формат", config.LogConfig{Level: "warn", Output: config.LogOutputStdout, Format: config.LogFormatText},
+			logrus.WarnLevel, true},
+		{"неверный уровень заменяется на info", config.LogConfig{Level: "verbose", Output: config.LogOutputStdout},
+			logrus.InfoLevel, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			output, err := Setup(logger, tt.cfg)
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			defer output.Close()
+
+			if logger.GetLevel() != tt.wantLevel {
+				t.Errorf("уровень %v, ожидался %v", logger.GetLevel(), tt.wantLevel)
+			}
+			if _, text := logger.Formatter.(*logrus.TextFormatter); text != tt.wantText {
+				t.Errorf("формат %T, ожидался текстовый: %v", logger.Formatter, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "subscriptions.log")
+	logger := logrus.New()
+
+	output, err := Setup(logger, config.LogConfig{Level: "info", Output: config.LogOutputFile, File: path, MaxSizeMB: 1})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	logger.Info("запись в файл")
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("файл логов не создан: %v", err)
+	}
+	if !strings.Contains(string(data), "запись в файл") {
+		t.Errorf("в файле логов нет записи: %s", data)
+	}
+}
+
+func TestSetupReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.log")
+	logger := logrus.New()
+
+	output, err := Setup(logger, config.LogConfig{Level: "info", Output: config.LogOutputFile, File: path, MaxSizeMB: 1})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	defer output.Close()
+
+	// Внешний logrotate переименовывает файл, после Reopen записи идут в новый файл по исходному пути
+	logger.Info("до ротации")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := output.Reopen(); err != nil {
+		t.Fatalf("Reopen() error = %v", err)
+	}
+	logger.Info("после ротации")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("файл логов не открыт заново: %v", err)
+	}
+	if strings.Contains(string(data), "до ротации") || !strings.Contains(string(data), "после ротации") {
+		t.Errorf("содержимое нового файла: %s", data)
+	}
+}
+
+func TestSetupErrors(t *testing.T) {
+	// Каталог логов нельзя создать: по пути каталога лежит обычный файл
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.LogConfig
+	}{
+		{"неизвестный приёмник", config.LogConfig{Output: "syslog"}},
+		{"недоступный файл", config.LogConfig{Output: config.LogOutputFile, File: filepath.Join(blocker, "subscriptions.log")}},
+		{"недоступный файл вместе с stdout", config.LogConfig{Output: config.LogOutputBoth, File: filepath.Join(blocker, "subscriptions.log")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			out := logger.Out
+
+			if _, err := Setup(logger, tt.cfg); err == nil {
+				t.Fatal("Setup() без ошибки")
+			}
+			// Приёмник не меняется, и записи продолжают выводиться в stderr
+			if logger.Out != out {
+				t.Error("приёмник логов изменён при ошибке настройки")
+			}
+		})
+	}
+}
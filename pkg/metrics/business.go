@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Максимальное время запроса статистики подписок при сборе метрик
+const statsTimeout = 5 * time.Second
+
+// businessCollector запрашивает статистику действующих подписок в момент сбора метрик
+type businessCollector struct {
+	repo   repository.Repository
+	logger *logrus.Logger
+
+	activeSubscriptions *prometheus.Desc
+	monthlySpend        *prometheus.Desc
+}
+
+// NewBusinessCollector создаёт сборщик метрик действующих подписок по сервисам
+func NewBusinessCollector(repo repository.Repository, logger *logrus.Logger) prometheus.Collector {
+	return &businessCollector{
+		repo:   repo,
+		logger: logger,
+		activeSubscriptions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "active_subscriptions"),
+			"Количество подписок, действующих в текущем месяце",
+			[]string{"service_name"}, nil),
+		monthlySpend: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "monthly_recurring_spend_rubles"),
+			"Суммарная месячная стоимость подписок, действующих в текущем месяце",
+			[]string{"service_name"}, nil),
+	}
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.activeSubscriptions
+	ch <- c.monthlySpend
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	stats, err := c.repo.ServiceStats(ctx)
+	if err != nil {
+		c.logger.WithError(err).Error("Ошибка при сборе метрик подписок")
+		ch <- prometheus.NewInvalidMetric(c.activeSubscriptions, err)
+		return
+	}
+
+	for _, stat := range stats {
+		ch <- prometheus.MustNewConstMetric(c.activeSubscriptions, prometheus.GaugeValue, float64(stat.ActiveCount), stat.ServiceName)
+		ch <- prometheus.MustNewConstMetric(c.monthlySpend, prometheus.GaugeValue, float64(stat.MonthlySpend), stat.ServiceName)
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Префикс имён метрик сервиса
+const namespace = "subscription_service"
+
+// Metrics - реестр метрик сервиса в формате Prometheus
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	repoDuration *prometheus.HistogramVec
+	repoErrors   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Количество обработанных HTTP-запросов",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Время обработки HTTP-запросов",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Время выполнения методов репозитория",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method"}),
+		repoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_errors_total",
+			Help:      "Количество ошибок методов репозитория по кодам ошибок",
+		}, []string{"method", "code"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.repoDuration,
+		m.repoErrors,
+	)
+
+	return m
+}
+
+// Handler отдаёт метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware считает HTTP-запросы и время их обработки по шаблону маршрута и коду ответа.
+// Шаблон маршрута (например, /subscriptions/by-id/:id) не зависит от параметров пути
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		labels := prometheus.Labels{
+			"method": ctx.Request.Method,
+			"route":  route,
+			"status": strconv.Itoa(ctx.Writer.Status()),
+		}
+
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Register добавляет в реестр дополнительные сборщики метрик
+func (m *Metrics) Register(collectors ...prometheus.Collector) {
+	m.registry.MustRegister(collectors...)
+}
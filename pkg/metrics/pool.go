@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector снимает статистику пула соединений pgx в момент сбора метрик
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	emptyAcquireWaitTime *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+}
+
+// NewPoolCollector создаёт сборщик метрик пула соединений с БД
+func NewPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		pool:                 pool,
+		acquiredConns:        desc("acquired_conns", "Количество выданных соединений"),
+		idleConns:            desc("idle_conns", "Количество простаивающих соединений"),
+		totalConns:           desc("total_conns", "Общее количество соединений"),
+		maxConns:             desc("max_conns", "Максимальный размер пула"),
+		acquireCount:         desc("acquire_total", "Количество успешных получений соединения"),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Суммарное время получения соединений"),
+		emptyAcquireCount:    desc("empty_acquire_total", "Количество получений соединения с ожиданием свободного"),
+		emptyAcquireWaitTime: desc("empty_acquire_wait_seconds_total", "Суммарное время ожидания свободного соединения"),
+		canceledAcquireCount: desc("canceled_acquire_total", "Количество отменённых получений соединения"),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquireCount
+	ch <- c.emptyAcquireWaitTime
+	ch <- c.canceledAcquireCount
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireWaitTime, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
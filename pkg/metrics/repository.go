@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// instrumentedRepo замеряет время выполнения и считает ошибки методов репозитория
+type instrumentedRepo struct {
+	repo    repository.Repository
+	metrics *Metrics
+}
+
+// Repository оборачивает репозиторий подписок сбором метрик
+func (m *Metrics) Repository(repo repository.Repository) repository.Repository {
+	return &instrumentedRepo{repo: repo, metrics: m}
+}
+
+// observe записывает длительность вызова метода и код ошибки, если она есть
+func (r *instrumentedRepo) observe(method string, start time.Time, err error) {
+	r.metrics.repoDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err == nil {
+		return
+	}
+
+	code := apperr.CodeInternal
+	if appErr, ok := apperr.As(err); ok {
+		code = appErr.Code
+	}
+	r.metrics.repoErrors.WithLabelValues(method, code).Inc()
+}
+
+func (r *instrumentedRepo) List(ctx context.Context, filter *model.ListFilter) ([]*model.Subscription, error) {
+	start := time.Now()
+	result, err := r.repo.List(ctx, filter)
+	r.observe("List", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) Count(ctx context.Context, filter *model.ListFilter) (int, error) {
+	start := time.Now()
+	result, err := r.repo.Count(ctx, filter)
+	r.observe("Count", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) Create(ctx context.Context, sub *model.Subscription) error {
+	start := time.Now()
+	err := r.repo.Create(ctx, sub)
+	r.observe("Create", start, err)
+	return err
+}
+
+func (r *instrumentedRepo) Get(ctx context.Context, userID *uuid.UUID, serviceName *string, includeDeleted bool) (*model.Subscription, error) {
+	start := time.Now()
+	result, err := r.repo.Get(ctx, userID, serviceName, includeDeleted)
+	r.observe("Get", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.Subscription, error) {
+	start := time.Now()
+	result, err := r.repo.GetByID(ctx, id, includeDeleted)
+	r.observe("GetByID", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) GetDeleted(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error) {
+	start := time.Now()
+	result, err := r.repo.GetDeleted(ctx, userID, serviceName)
+	r.observe("GetDeleted", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) FindOverlapping(ctx context.Context, period *model.SubscriptionPeriod) (*model.Subscription, error) {
+	start := time.Now()
+	result, err := r.repo.FindOverlapping(ctx, period)
+	r.observe("FindOverlapping", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) Update(ctx context.Context, sub *model.Subscription) (*model.Subscription, error) {
+	start := time.Now()
+	result, err := r.repo.Update(ctx, sub)
+	r.observe("Update", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	start := time.Now()
+	err := r.repo.Delete(ctx, id, version)
+	r.observe("Delete", start, err)
+	return err
+}
+
+func (r *instrumentedRepo) Restore(ctx context.Context, id uuid.UUID, version int) (*model.Subscription, error) {
+	start := time.Now()
+	result, err := r.repo.Restore(ctx, id, version)
+	r.observe("Restore", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	start := time.Now()
+	result, err := r.repo.Purge(ctx, deletedBefore)
+	r.observe("Purge", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) Import(ctx context.Context, rows []*model.ImportRow, mode string) error {
+	start := time.Now()
+	err := r.repo.Import(ctx, rows, mode)
+	r.observe("Import", start, err)
+	return err
+}
+
+func (r *instrumentedRepo) GetTotal(ctx context.Context, req *model.TotalSubscription) (*model.TotalResponse, error) {
+	start := time.Now()
+	result, err := r.repo.GetTotal(ctx, req)
+	r.observe("GetTotal", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) GetTotalBreakdown(ctx context.Context, req *model.BreakdownSubscription) ([]*model.BreakdownItem, error) {
+	start := time.Now()
+	result, err := r.repo.GetTotalBreakdown(ctx, req)
+	r.observe("GetTotalBreakdown", start, err)
+	return result, err
+}
+
+func (r *instrumentedRepo) ServiceStats(ctx context.Context) ([]*model.ServiceStats, error) {
+	start := time.Now()
+	result, err := r.repo.ServiceStats(ctx)
+	r.observe("ServiceStats", start, err)
+	return result, err
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// APIKeyHeader - заголовок с ключом API внутренних сервисов
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator проверяет ключ API и возвращает внутренний сервис, выполняющий запрос
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (*auth.Principal, error)
+}
+
+// Authenticate проверяет ключ API из заголовка X-API-Key или JWT из заголовка Authorization: Bearer
+// и сохраняет пользователя в контексте запроса. Запросы без учётных данных или с недействительными
+// учётными данными отклоняются с кодом 401. Без verifier (ключи проверки JWT не заданы)
+// принимаются только ключи API
+func Authenticate(verifier *auth.Verifier, apiKeys APIKeyAuthenticator, logger *logrus.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var principal *auth.Principal
+		var fields logrus.Fields
+
+		if rawKey := ctx.GetHeader(APIKeyHeader); rawKey != "" {
+			var err error
+			principal, err = apiKeys.Authenticate(ctx.Request.Context(), rawKey)
+			if err != nil {
+				ctx.Error(err)
+				ctx.Abort()
+				return
+			}
+			fields = logrus.Fields{"api_key_id": principal.APIKeyID.String()}
+		} else if verifier == nil {
+			ctx.Error(apperr.Unauthorized(apperr.CodeAPIKeyRequired, "Требуется ключ API в заголовке X-API-Key"))
+			ctx.Abort()
+			return
+		} else {
+			scheme, token, found := strings.Cut(ctx.GetHeader("Authorization"), " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				ctx.Header("WWW-Authenticate", `Bearer`)
+				ctx.Error(apperr.Unauthorized(apperr.CodeUnauthorized, "Требуется токен доступа в заголовке Authorization"))
+				ctx.Abort()
+				return
+			}
+
+			var err error
+			principal, err = verifier.Verify(strings.TrimSpace(token))
+			if err != nil {
+				ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				ctx.Error(apperr.Wrap(apperr.ErrUnauthorized, apperr.CodeInvalidToken, "Токен доступа недействителен", err))
+				ctx.Abort()
+				return
+			}
+			fields = logrus.Fields{"user_id": principal.UserID.String()}
+		}
+
+		reqCtx := auth.WithPrincipal(ctx.Request.Context(), principal)
+		reqCtx = logging.WithFields(reqCtx, logger, fields)
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		ctx.Next()
+	}
+}
+
+// RequireScope пропускает запросы с ключом API, только если ключу разрешена область доступа scope.
+// На запросы пользователей с JWT не влияет
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := auth.FromContext(ctx.Request.Context())
+		if ok && !principal.HasScope(scope) {
+			ctx.Error(apperr.Forbidden(apperr.CodeInsufficientScope, "Ключу API не разрешена эта операция").
+				AddParam(APIKeyHeader, apperr.FieldScopeRequired, scope, "Требуется область доступа "+scope))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/config"
+	"subscription_service/pkg/i18n"
+	"subscription_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var testSecret = strings.Repeat("s", 32)
+
+// fakeAPIKeys принимает только ключи из keys, которых нет в revoked
+type fakeAPIKeys struct {
+	keys    map[string]*auth.Principal
+	revoked map[string]bool
+}
+
+func (f fakeAPIKeys) Authenticate(_ context.Context, rawKey string) (*auth.Principal, error) {
+	principal, ok := f.keys[rawKey]
+	if !ok || f.revoked[rawKey] {
+		return nil, apperr.Unauthorized(apperr.CodeInvalidAPIKey, "Ключ API недействителен или отозван")
+	}
+	return principal, nil
+}
+
+// signToken подписывает токен HS256 пользователя userID тестовым секретом
+func signToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": userID.String(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// serveAuthenticated выполняет запрос через Authenticate и возвращает ответ
+// и пользователя, которого увидел обработчик
+func serveAuthenticated(t *testing.T, verifier *auth.Verifier, apiKeys APIKeyAuthenticator, headers map[string]string, handlers ...gin.HandlerFunc) (*httptest.ResponseRecorder, *auth.Principal) {
+	t.Helper()
+
+	var principal *auth.Principal
+	router := gin.New()
+	router.Use(Language(i18n.Russian), ErrorHandler(newTestLogger()), Authenticate(verifier, apiKeys, newTestLogger()))
+	handlers = append(handlers, func(ctx *gin.Context) {
+		principal, _ = auth.FromContext(ctx.Request.Context())
+		ctx.Status(http.StatusNoContent)
+	})
+	router.GET("/test", handlers...)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec, principal
+}
+
+// problemCode возвращает код ошибки из ответа problem+json
+func problemCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var problem model.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("тело ответа не в формате problem+json: %v: %s", err, rec.Body.String())
+	}
+	return problem.Code
+}
+
+func TestAuthenticate(t *testing.T) {
+	verifier, err := auth.NewVerifier(config.AuthConfig{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, keyID := uuid.New(), uuid.New()
+	apiKeys := fakeAPIKeys{keys: map[string]*auth.Principal{"sk_valid": {APIKeyID: &keyID}}}
+	token := signToken(t, userID)
+
+	tests := []struct {
+		name       string
+		verifier   *auth.Verifier
+		headers    map[string]string
+		wantStatus int
+		wantCode   string
+		wantUser   *uuid.UUID
+		wantKey    *uuid.UUID
+	}{
+		{"токен пользователя", verifier, map[string]string{"Authorization": "Bearer " + token}, http.StatusNoContent, "", &userID, nil},
+		{"ключ API", verifier, map[string]string{APIKeyHeader: "sk_valid"}, http.StatusNoContent, "", nil, &keyID},
+		{"без учётных данных", verifier, nil, http.StatusUnauthorized, apperr.CodeUnauthorized, nil, nil},
+		{"неверный токен", verifier, map[string]string{"Authorization": "Bearer " + token + "x"}, http.StatusUnauthorized, apperr.CodeInvalidToken, nil, nil},
+		{"только ключи API: ключ", nil, map[string]string{APIKeyHeader: "sk_valid"}, http.StatusNoContent, "", nil, &keyID},
+		{"только ключи API: токен", nil, map[string]string{"Authorization": "Bearer " + token}, http.StatusUnauthorized, apperr.CodeAPIKeyRequired, nil, nil},
+		{"только ключи API: без учётных данных", nil, nil, http.StatusUnauthorized, apperr.CodeAPIKeyRequired, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, principal := serveAuthenticated(t, tt.verifier, apiKeys, tt.headers)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("код ответа %d, ожидался %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := problemCode(t, rec); code != tt.wantCode {
+					t.Errorf("code = %q, ожидался %q", code, tt.wantCode)
+				}
+				return
+			}
+			if tt.wantUser != nil && principal.UserID != *tt.wantUser {
+				t.Errorf("пользователь %v, ожидался %v", principal.UserID, *tt.wantUser)
+			}
+			if tt.wantKey != nil && (principal.APIKeyID == nil || *principal.APIKeyID != *tt.wantKey) {
+				t.Errorf("ключ API %v, ожидался %v", principal.APIKeyID, *tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	verifier, err := auth.NewVerifier(config.AuthConfig{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerID, writerID := uuid.New(), uuid.New()
+	apiKeys := fakeAPIKeys{
+		keys: map[string]*auth.Principal{
+			"sk_reader":  {APIKeyID: &readerID, Scopes: []string{auth.ScopeSubscriptionsRead}},
+			"sk_writer":  {APIKeyID: &writerID, Scopes: []string{auth.ScopeSubscriptionsRead, auth.ScopeSubscriptionsWrite}},
+			"sk_revoked": {APIKeyID: &writerID, Scopes: []string{auth.ScopeSubscriptionsWrite}},
+		},
+		revoked: map[string]bool{"sk_revoked": true},
+	}
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantCode   string
+	}{
+		{"ключ с областью доступа", map[string]string{APIKeyHeader: "sk_writer"}, http.StatusNoContent, ""},
+		{"ключ без области доступа", map[string]string{APIKeyHeader: "sk_reader"}, http.StatusForbidden, apperr.CodeInsufficientScope},
+		{"отозванный ключ", map[string]string{APIKeyHeader: "sk_revoked"}, http.StatusUnauthorized, apperr.CodeInvalidAPIKey},
+		{"неверный ключ", map[string]string{APIKeyHeader: "sk_unknown"}, http.StatusUnauthorized, apperr.CodeInvalidAPIKey},
+		{"пользователь с токеном", map[string]string{"Authorization": "Bearer " + signToken(t, uuid.New())}, http.StatusNoContent, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, _ := serveAuthenticated(t, verifier, apiKeys, tt.headers, RequireScope(auth.ScopeSubscriptionsWrite))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("код ответа %d, ожидался %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := problemCode(t, rec); code != tt.wantCode {
+					t.Errorf("code = %q, ожидался %q", code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/i18n"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Тип содержимого ответа с ошибкой (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// ErrorHandler формирует ответ по последней ошибке, переданной обработчиком через ctx.Error.
+// Ошибки apperr отображаются в соответствующие коды ответа, остальные считаются внутренними
+func ErrorHandler(logger *logrus.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		WriteProblem(ctx, logger, ctx.Errors.Last().Err)
+	}
+}
+
+// Recovery перехватывает панику в обработчиках и отвечает внутренней ошибкой в формате problem+json
+func Recovery(logger *logrus.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, recovered any) {
+		logging.FromContext(ctx.Request.Context(), logger).
+			WithField("stack", string(debug.Stack())).Error("Паника при обработке запроса")
+		WriteProblem(ctx, logger, fmt.Errorf("panic: %v", recovered))
+		ctx.Abort()
+	})
+}
+
+// NoRoute отвечает ошибкой на запрос к несуществующему маршруту
+func NoRoute() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Error(apperr.NotFound(apperr.CodeRouteNotFound, "Маршрут не найден"))
+	}
+}
+
+// WriteProblem записывает ответ с ошибкой err в формате application/problem+json.
+// Подробности внутренних ошибок только логируются, клиент получает общее сообщение.
+// Сообщения переводятся на язык, выбранный middleware Language
+func WriteProblem(ctx *gin.Context, logger *logrus.Logger, err error) {
+	requestID := ctx.GetString(RequestIDKey)
+	entry := logging.FromContext(ctx.Request.Context(), logger).WithError(err)
+
+	appErr, ok := apperr.As(err)
+	if !ok {
+		entry.Error("Внутренняя ошибка при обработке запроса")
+		appErr = apperr.New(errors.New("internal"), apperr.CodeInternal, "Внутренняя ошибка сервера")
+	}
+
+	status := statusOf(appErr)
+	if ok {
+		entry.WithFields(logrus.Fields{
+			"status": status,
+			"code":   appErr.Code,
+		}).Warn(appErr.Message)
+	}
+
+	lang := ctx.GetString(LanguageKey)
+	problem := model.Problem{
+		Type:      problemType(appErr.Code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    appErr.Message,
+		Instance:  ctx.Request.URL.RequestURI(),
+		Code:      appErr.Code,
+		RequestID: requestID,
+		Errors:    localizeFields(lang, appErr.Fields),
+		Conflict:  appErr.Conflict,
+	}
+	if title, ok := i18n.StatusTitle(lang, status); ok {
+		problem.Title = title
+	}
+	problem.Detail = i18n.Translate(lang, appErr.Code, appErr.Param, appErr.Message)
+
+	ctx.Header("Content-Type", problemContentType)
+	ctx.JSON(status, problem)
+}
+
+// LocalizeError переводит сообщение ошибки с кодом code и ошибки полей на язык запроса.
+// Используется для ошибок, которые возвращаются в теле успешного ответа, например в отчёте импорта
+func LocalizeError(ctx *gin.Context, code, message string, fields []model.FieldError) (string, []model.FieldError) {
+	lang := ctx.GetString(LanguageKey)
+	return i18n.Translate(lang, code, "", message), localizeFields(lang, fields)
+}
+
+// localizeFields переводит сообщения ошибок полей на язык lang.
+// Если перевода для кода нет, остаётся исходное сообщение
+func localizeFields(lang string, fields []model.FieldError) []model.FieldError {
+	if len(fields) == 0 {
+		return fields
+	}
+
+	localized := make([]model.FieldError, len(fields))
+	for i, field := range fields {
+		field.Message = i18n.Translate(lang, field.Code, field.Param, field.Message)
+		localized[i] = field
+	}
+	return localized
+}
+
+// problemType возвращает URI типа ошибки, например /problems/subscription-exists
+func problemType(code string) string {
+	return "/problems/" + strings.ReplaceAll(strings.ToLower(code), "_", "-")
+}
+
+// statusOf возвращает код HTTP-ответа для категории ошибки
+func statusOf(err *apperr.Error) int {
+	switch {
+	case errors.Is(err, apperr.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperr.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, apperr.ErrUnprocessable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, apperr.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, apperr.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, apperr.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperr.ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, apperr.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, apperr.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, apperr.ErrRequestTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/i18n"
+	"subscription_service/pkg/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestLogger возвращает логгер, записи которого не выводятся
+func newTestLogger() *logrus.Logger {
+	logger, _ := test.NewNullLogger()
+	return logger
+}
+
+// serveError выполняет запрос к обработчику, возвращающему err, и разбирает ответ
+func serveError(t *testing.T, acceptLanguage string, err error) (*httptest.ResponseRecorder, model.Problem) {
+	t.Helper()
+
+	router := gin.New()
+	router.Use(Language(i18n.Russian), ErrorHandler(newTestLogger()))
+	router.GET("/test", func(ctx *gin.Context) {
+		ctx.Error(err)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var problem model.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("тело ответа не в формате problem+json: %v: %s", err, rec.Body.String())
+	}
+	return rec, problem
+}
+
+func TestWriteProblem(t *testing.T) {
+	validation := func() error {
+		return apperr.Validation(apperr.CodeInvalidQueryParams, "Неверные параметры запроса").
+			AddParam("sort", apperr.FieldNotAllowed, "price", "Допустимые значения: price").
+			Add("cursor", apperr.FieldInvalidCursor, "Курсор повреждён или получен для другой сортировки")
+	}
+
+	tests := []struct {
+		name       string
+		language   string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantTitle  string
+		wantDetail string
+		wantFields []string
+	}{
+		{
+			name:       "не найдено, ru",
+			err:        apperr.NotFound(apperr.CodeSubscriptionNotFound, "Подписка не найдена"),
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperr.CodeSubscriptionNotFound,
+			wantTitle:  "Не найдено",
+			wantDetail: "Подписка не найдена",
+		},
+		{
+			name:       "не найдено, en",
+			language:   "en-US,en;q=0.9",
+			err:        apperr.NotFound(apperr.CodeSubscriptionNotFound, "Подписка не найдена"),
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperr.CodeSubscriptionNotFound,
+			wantTitle:  "Not Found",
+			wantDetail: "Subscription not found",
+		},
+		{
+			name:       "ошибки полей, ru",
+			err:        validation(),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperr.CodeInvalidQueryParams,
+			wantTitle:  "Неверный запрос",
+			wantDetail: "Неверные параметры запроса",
+			wantFields: []string{"Допустимые значения: price", "Курсор повреждён или получен для другой сортировки"},
+		},
+		{
+			name:       "ошибки полей, en",
+			language:   "en",
+			err:        validation(),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperr.CodeInvalidQueryParams,
+			wantTitle:  "Bad Request",
+			wantDetail: "Invalid query parameters",
+			wantFields: []string{"Allowed values: price", "The cursor is corrupted or was issued for a different sort order"},
+		},
+		{
+			name:       "параметр сообщения, en",
+			language:   "en",
+			err:        apperr.PreconditionFailed(apperr.CodeVersionMismatch, "Подписка была изменена, текущая версия 4").WithParam("4"),
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   apperr.CodeVersionMismatch,
+			wantTitle:  "Precondition Failed",
+			wantDetail: "The subscription has been modified, the current version is 4",
+		},
+		{
+			name:       "подробности без перевода сохраняются",
+			language:   "en",
+			err:        apperr.Validation(apperr.CodeInvalidRequestBody, "Неверная строка CSV"),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperr.CodeInvalidRequestBody,
+			wantTitle:  "Bad Request",
+			wantDetail: "Неверная строка CSV",
+		},
+		{
+			name:       "внутренняя ошибка скрывается, en",
+			language:   "en",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   apperr.CodeInternal,
+			wantTitle:  "Internal Server Error",
+			wantDetail: "Internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, problem := serveError(t, tt.language, tt.err)
+
+			if rec.Code != tt.wantStatus || problem.Status != tt.wantStatus {
+				t.Errorf("код ответа %d (в теле %d), ожидался %d", rec.Code, problem.Status, tt.wantStatus)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != problemContentType {
+				t.Errorf("Content-Type = %q", contentType)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code = %q, ожидался %q", problem.Code, tt.wantCode)
+			}
+			if problem.Title != tt.wantTitle {
+				t.Errorf("title = %q, ожидался %q", problem.Title, tt.wantTitle)
+			}
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, ожидался %q", problem.Detail, tt.wantDetail)
+			}
+			if len(problem.Errors) != len(tt.wantFields) {
+				t.Fatalf("errors = %+v, ожидалось %d ошибок", problem.Errors, len(tt.wantFields))
+			}
+			for i, want := range tt.wantFields {
+				if problem.Errors[i].Message != want {
+					t.Errorf("errors[%d].message = %q, ожидалось %q", i, problem.Errors[i].Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		kind error
+		want int
+	}{
+		{apperr.ErrNotFound, http.StatusNotFound},
+		{apperr.ErrConflict, http.StatusConflict},
+		{apperr.ErrValidation, http.StatusBadRequest},
+		{apperr.ErrUnprocessable, http.StatusUnprocessableEntity},
+		{apperr.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType},
+		{apperr.ErrUnauthorized, http.StatusUnauthorized},
+		{apperr.ErrForbidden, http.StatusForbidden},
+		{apperr.ErrTooManyRequests, http.StatusTooManyRequests},
+		{apperr.ErrPreconditionFailed, http.StatusPreconditionFailed},
+		{apperr.ErrPreconditionRequired, http.StatusPreconditionRequired},
+		{apperr.ErrRequestTooLarge, http.StatusRequestEntityTooLarge},
+		{errors.New("unknown kind"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind.Error(), func(t *testing.T) {
+			if got := statusOf(apperr.New(tt.kind, "CODE", "Ошибка")); got != tt.want {
+				t.Errorf("statusOf() = %d, ожидался %d", got, tt.want)
+			}
+		})
+	}
+}
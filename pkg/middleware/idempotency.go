@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// Заголовок ответа, возвращённого повторно по ключу идемпотентности
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// Тело запроса с ключом идемпотентности читается в память целиком, поэтому его размер ограничен
+	maxIdempotentBodySize = 4 << 20
+)
+
+// IdempotencyStore сохраняет ключи идемпотентности и ответы на запросы с ними
+type IdempotencyStore interface {
+	Begin(ctx context.Context, clientKey, key, requestHash string) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *model.IdempotencyRecord) error
+	Release(ctx context.Context, clientKey, key string) error
+}
+
+// Заголовки ответа, которые сохраняются вместе с телом и возвращаются при повторе запроса
+var idempotentHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency выполняет изменяющий запрос с заголовком Idempotency-Key не больше одного раза.
+// Ответ на первый запрос сохраняется, повтор с тем же ключом и тем же запросом получает его
+// без повторного выполнения, повтор с другим запросом - код 422. Ответы с кодом 5xx не сохраняются,
+// чтобы запрос можно было повторить. Ключи разных клиентов не пересекаются, поэтому middleware
+// подключается после Authenticate
+func Idempotency(idempotency IdempotencyStore, logger *logrus.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutating(ctx.Request.Method) {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			ctx.Error(apperr.Validation(apperr.CodeInvalidIdempotencyKey, "Заголовок Idempotency-Key должен содержать от 1 до 255 символов"))
+			ctx.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			limit := strconv.Itoa(maxIdempotentBodySize)
+			ctx.Error(apperr.RequestTooLarge(apperr.CodeRequestTooLarge,
+				"Тело запроса с заголовком Idempotency-Key не должно превышать "+limit+" байт").WithParam(limit))
+			ctx.Abort()
+			return
+		}
+		if err != nil {
+			ctx.Error(apperr.Wrap(apperr.ErrValidation, apperr.CodeInvalidRequestBody, "Не удалось прочитать тело запроса", err))
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		client := clientKey(ctx)
+		stored, err := idempotency.Begin(ctx.Request.Context(), client, key, requestHash(ctx.Request, body))
+		if err != nil {
+			ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+		if stored != nil {
+			replay(ctx, stored)
+			ctx.Abort()
+			return
+		}
+
+		// Ответ сохраняется и после отмены запроса клиентом
+		storeCtx := context.WithoutCancel(ctx.Request.Context())
+		release := func() {
+			if err := idempotency.Release(storeCtx, client, key); err != nil {
+				logging.FromContext(storeCtx, logger).WithError(err).Error("Ошибка освобождения ключа идемпотентности")
+			}
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+
+		// При панике в обработчике ключ освобождается, чтобы запрос можно было повторить
+		completed := false
+		defer func() {
+			if !completed {
+				release()
+			}
+		}()
+
+		ctx.Next()
+
+		// Ответ с ошибкой записывается здесь, а не в ErrorHandler, чтобы сохранить его для повтора
+		if len(ctx.Errors) > 0 && !ctx.Writer.Written() {
+			WriteProblem(ctx, logger, ctx.Errors.Last().Err)
+		}
+		completed = true
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		header := make(map[string]string, len(idempotentHeaders))
+		for _, name := range idempotentHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				header[name] = value
+			}
+		}
+
+		err = idempotency.Complete(storeCtx, &model.IdempotencyRecord{
+			ClientKey:  client,
+			Key:        key,
+			StatusCode: status,
+			Header:     header,
+			Body:       recorder.body.Bytes(),
+		})
+		if err != nil {
+			logging.FromContext(storeCtx, logger).WithError(err).Error("Ошибка сохранения ответа по ключу идемпотентности")
+			release()
+		}
+	}
+}
+
+// replay возвращает сохранённый ответ на запрос
+func replay(ctx *gin.Context, stored *model.IdempotencyRecord) {
+	for name, value := range stored.Header {
+		ctx.Header(name, value)
+	}
+	ctx.Header(IdempotentReplayedHeader, "true")
+	ctx.Data(stored.StatusCode, stored.Header["Content-Type"], stored.Body)
+}
+
+// requestHash вычисляет SHA-256 метода, адреса, типа содержимого и тела запроса
+func requestHash(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{req.Method, req.URL.RequestURI(), req.Header.Get("Content-Type")} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// responseRecorder копирует тело ответа для сохранения по ключу идемпотентности
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
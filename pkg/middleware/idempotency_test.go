@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore хранит ключи идемпотентности в памяти
+type memoryIdempotencyStore struct {
+	records map[string]*model.IdempotencyRecord
+}
+
+func (s *memoryIdempotencyStore) Begin(_ context.Context, clientKey, key, requestHash string) (*model.IdempotencyRecord, error) {
+	record, ok := s.records[clientKey+"|"+key]
+	switch {
+	case !ok:
+		s.records[clientKey+"|"+key] = &model.IdempotencyRecord{ClientKey: clientKey, Key: key, RequestHash: requestHash}
+		return nil, nil
+	case record.RequestHash != requestHash:
+		return nil, apperr.Unprocessable(apperr.CodeIdempotencyKeyReused, "Ключ идемпотентности уже использован для другого запроса")
+	case !record.Completed():
+		return nil, apperr.Conflict(apperr.CodeIdempotencyInProgress, "Запрос с этим ключом идемпотентности ещё выполняется, повторите позже", nil)
+	}
+	return record, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, record *model.IdempotencyRecord) error {
+	stored := s.records[record.ClientKey+"|"+record.Key]
+	stored.StatusCode, stored.Header, stored.Body = record.StatusCode, record.Header, record.Body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, clientKey, key string) error {
+	delete(s.records, clientKey+"|"+key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(ErrorHandler(newTestLogger()))
+	router.Use(Idempotency(&memoryIdempotencyStore{records: map[string]*model.IdempotencyRecord{}}, newTestLogger()))
+	router.POST("/subscriptions", func(ctx *gin.Context) {
+		calls++
+		if ctx.Query("fail") != "" {
+			ctx.Error(errors.New("connection refused"))
+			return
+		}
+		ctx.Header("Location", "/subscriptions/1")
+		ctx.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		key          string
+		body         string
+		wantStatus   int
+		wantCalls    int
+		wantReplayed bool
+	}{
+		{"без ключа", "/subscriptions", "", `{"price":1}`, http.StatusCreated, 1, false},
+		{"первый запрос", "/subscriptions", "key-1", `{"price":1}`, http.StatusCreated, 2, false},
+		{"повтор", "/subscriptions", "key-1", `{"price":1}`, http.StatusCreated, 2, true},
+		{"повтор с другим телом", "/subscriptions", "key-1", `{"price":2}`, http.StatusUnprocessableEntity, 2, false},
+		{"ошибка сервера не сохраняется", "/subscriptions?fail=1", "key-2", `{}`, http.StatusInternalServerError, 3, false},
+		{"повтор после ошибки сервера", "/subscriptions?fail=1", "key-2", `{}`, http.StatusInternalServerError, 4, false},
+		{"слишком длинный ключ", "/subscriptions", strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`, http.StatusBadRequest, 4, false},
+		{"слишком большое тело", "/subscriptions", "key-3", strings.Repeat(" ", maxIdempotentBodySize+1), http.StatusRequestEntityTooLarge, 4, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("код %d, ожидался %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("обработчик вызван %d раз, ожидалось %d", calls, tt.wantCalls)
+			}
+			if replayed := rec.Header().Get(IdempotentReplayedHeader) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("%s = %v, ожидалось %v", IdempotentReplayedHeader, replayed, tt.wantReplayed)
+			}
+			if tt.wantReplayed && (rec.Header().Get("Location") != "/subscriptions/1" || rec.Body.String() != `{"call":2}`) {
+				t.Errorf("повтор вернул другой ответ: %v %s", rec.Header(), rec.Body.String())
+			}
+		})
+	}
+}
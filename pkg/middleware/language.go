@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"subscription_service/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LanguageKey - ключ контекста запроса с выбранным языком ответа
+const LanguageKey = "language"
+
+// Language выбирает язык сообщений по заголовку Accept-Language.
+// Если клиент не указал поддерживаемый язык, используется defaultLang
+func Language(defaultLang string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		lang := i18n.ParseAcceptLanguage(ctx.GetHeader("Accept-Language"), defaultLang)
+
+		ctx.Set(LanguageKey, lang)
+		ctx.Header("Content-Language", lang)
+		ctx.Writer.Header().Add("Vary", "Accept-Language")
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"subscription_service/pkg/logging"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Logging сохраняет в контексте запроса запись логгера с идентификатором запроса,
+// идентификатором трассировки и ID пользователя, чтобы все записи одного запроса
+// в сервисе и репозитории можно было связать, и после обработки пишет запись в журнал доступа.
+// Должен подключаться после RequestID и middleware трассировки
+func Logging(logger *logrus.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		fields := logrus.Fields{"request_id": ctx.GetString(RequestIDKey)}
+		if spanCtx := trace.SpanContextFromContext(ctx.Request.Context()); spanCtx.HasTraceID() {
+			fields["trace_id"] = spanCtx.TraceID().String()
+		}
+		if userID := requestUserID(ctx); userID != "" {
+			fields["user_id"] = userID
+		}
+		ctx.Request = ctx.Request.WithContext(
+			logging.WithFields(ctx.Request.Context(), logger, fields))
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		logging.FromContext(ctx.Request.Context(), logger).WithFields(logrus.Fields{
+			"method":     ctx.Request.Method,
+			"route":      route,
+			"status":     ctx.Writer.Status(),
+			"duration":   time.Since(start),
+			"client_ip":  ctx.ClientIP(),
+			"user_agent": ctx.Request.UserAgent(),
+		}).Info("HTTP request")
+	}
+}
+
+// requestUserID возвращает ID пользователя из пути или параметров запроса, если он указан верно
+func requestUserID(ctx *gin.Context) string {
+	userID := ctx.Param("user_id")
+	if userID == "" {
+		userID = ctx.Query("user_id")
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return ""
+	}
+	return userID
+}
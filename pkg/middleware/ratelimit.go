@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/config"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/ratelimit"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimit ограничивает частоту запросов клиента по алгоритму token bucket.
+// Клиент определяется по ключу API, пользователю JWT или IP-адресу, поэтому middleware
+// подключается после Authenticate. Маршруты с собственным ограничением из конфигурации
+// считаются отдельно, остальные маршруты делят общее ограничение по умолчанию.
+// Состояние ограничения возвращается в заголовках RateLimit-*, при превышении - код 429
+// и Retry-After. Если хранилище недоступно, запрос пропускается
+func RateLimit(store ratelimit.Store, cfg config.RateLimitConfig, logger *logrus.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		bucket, limit := "default", cfg.Default
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		if routeLimit, ok := cfg.Routes[route]; ok {
+			bucket, limit = route, routeLimit
+		}
+
+		if takeRateLimit(ctx, store, bucket+"|"+clientKey(ctx), limit, logger) {
+			ctx.Next()
+		}
+	}
+}
+
+// IPRateLimit ограничивает частоту всех запросов с одного IP-адреса. Middleware подключается
+// до Authenticate, чтобы запросы с неверными токенами и ключами API тоже учитывались
+// и их нельзя было перебирать без ограничения
+func IPRateLimit(store ratelimit.Store, limit config.RateLimit, logger *logrus.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if takeRateLimit(ctx, store, "ip|ip:"+ctx.ClientIP(), limit, logger) {
+			ctx.Next()
+		}
+	}
+}
+
+// takeRateLimit списывает запрос из корзины key и записывает состояние ограничения в заголовки.
+// Возвращает false, если запрос отклонён с кодом 429
+func takeRateLimit(ctx *gin.Context, store ratelimit.Store, key string, limit config.RateLimit, logger *logrus.Logger) bool {
+	result, err := store.Take(ctx.Request.Context(), key, ratelimit.Limit{Requests: limit.Requests, Period: limit.Period})
+	if err != nil {
+		logging.FromContext(ctx.Request.Context(), logger).WithError(err).Error("Ошибка хранилища ограничения частоты запросов")
+		return true
+	}
+
+	ctx.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	ctx.Header("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(ceilSeconds(limit.Period)))
+
+	if !result.Allowed {
+		logging.FromContext(ctx.Request.Context(), logger).WithFields(logrus.Fields{
+			"rate_limit_key": key,
+			"retry_after":    result.RetryAfter.String(),
+		}).Warn("Превышена частота запросов")
+
+		ctx.Header("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+		ctx.Error(apperr.TooManyRequests(apperr.CodeRateLimitExceeded, "Слишком много запросов, повторите позже"))
+		ctx.Abort()
+		return false
+	}
+	return true
+}
+
+// clientKey определяет клиента: ключ API, пользователь JWT или IP-адрес без аутентификации
+func clientKey(ctx *gin.Context) string {
+	principal, ok := auth.FromContext(ctx.Request.Context())
+	switch {
+	case ok && principal.IsService():
+		return "api_key:" + principal.APIKeyID.String()
+	case ok:
+		return "user:" + principal.UserID.String()
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/config"
+	"subscription_service/pkg/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requestFrom выполняет запрос с IP-адреса remoteAddr
+func requestFrom(router *gin.Engine, method, path, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIPRateLimitCountsFailedAuthentication(t *testing.T) {
+	router := gin.New()
+	router.Use(ErrorHandler(newTestLogger()))
+	router.Use(IPRateLimit(ratelimit.NewMemoryStore(), config.RateLimit{Requests: 2, Period: time.Minute}, newTestLogger()))
+	// Аутентификация, отклоняющая любые учётные данные
+	router.Use(func(ctx *gin.Context) {
+		ctx.Error(apperr.Unauthorized(apperr.CodeInvalidToken, "Токен доступа недействителен"))
+		ctx.Abort()
+	})
+	router.GET("/subscriptions", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, status := range want {
+		rec := requestFrom(router, http.MethodGet, "/subscriptions", "192.0.2.1:1234")
+		if rec.Code != status {
+			t.Fatalf("запрос %d: код %d, ожидался %d", i+1, rec.Code, status)
+		}
+	}
+
+	rec := requestFrom(router, http.MethodGet, "/subscriptions", "192.0.2.1:1234")
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("нет заголовка Retry-After")
+	}
+
+	// Другой IP-адрес ограничивается отдельно
+	if rec := requestFrom(router, http.MethodGet, "/subscriptions", "192.0.2.2:1234"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("другой IP-адрес: код %d, ожидался %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Enabled: true,
+		Default: config.RateLimit{Requests: 2, Period: time.Minute},
+		Routes: map[string]config.RateLimit{
+			"GET /subscriptions/total": {Requests: 1, Period: time.Minute},
+		},
+	}
+	users := map[string]uuid.UUID{"alice": uuid.New(), "bob": uuid.New()}
+
+	router := gin.New()
+	router.Use(ErrorHandler(newTestLogger()))
+	router.Use(func(ctx *gin.Context) {
+		if userID, ok := users[ctx.GetHeader("X-User")]; ok {
+			ctx.Request = ctx.Request.WithContext(auth.WithPrincipal(ctx.Request.Context(), &auth.Principal{UserID: userID}))
+		}
+	})
+	router.Use(RateLimit(ratelimit.NewMemoryStore(), cfg, newTestLogger()))
+	router.GET("/subscriptions", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.GET("/subscriptions/total", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	tests := []struct {
+		name          string
+		user          string
+		path          string
+		wantStatus    int
+		wantRemaining string
+	}{
+		{"первый запрос", "alice", "/subscriptions", http.StatusOK, "1"},
+		{"второй запрос", "alice", "/subscriptions", http.StatusOK, "0"},
+		{"превышение общего ограничения", "alice", "/subscriptions", http.StatusTooManyRequests, "0"},
+		{"собственное ограничение маршрута", "alice", "/subscriptions/total", http.StatusOK, "0"},
+		{"превышение ограничения маршрута", "alice", "/subscriptions/total", http.StatusTooManyRequests, "0"},
+		{"другой пользователь", "bob", "/subscriptions", http.StatusOK, "1"},
+		{"без аутентификации по IP-адресу", "", "/subscriptions", http.StatusOK, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-User", tt.user)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("код %d, ожидался %d", rec.Code, tt.wantStatus)
+			}
+			if remaining := rec.Header().Get("RateLimit-Remaining"); remaining != tt.wantRemaining {
+				t.Errorf("RateLimit-Remaining = %q, ожидалось %q", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"subscription_service/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader - заголовок, в котором передаётся идентификатор запроса
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey - ключ идентификатора запроса в контексте gin
+	RequestIDKey = "request_id"
+
+	// Максимальная длина идентификатора запроса, переданного клиентом
+	maxRequestIDLength = 128
+)
+
+// RequestID использует идентификатор запроса из заголовка X-Request-ID или генерирует новый
+// и возвращает его в одноимённом заголовке ответа.
+// Слишком длинные идентификаторы и идентификаторы с недопустимыми символами заменяются новыми
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Set(RequestIDKey, requestID)
+		ctx.Header(RequestIDHeader, requestID)
+		ctx.Request = ctx.Request.WithContext(logging.WithRequestID(ctx.Request.Context(), requestID))
+
+		ctx.Next()
+	}
+}
+
+// validRequestID проверяет, что идентификатор состоит из латинских букв, цифр и символов - _ . :
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
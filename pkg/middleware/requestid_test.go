@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"UUID", uuid.NewString(), true},
+		{"допустимые символы", "trace-01_a.b:c", true},
+		{"максимальная длина", strings.Repeat("a", maxRequestIDLength), true},
+		{"пустой", "", false},
+		{"слишком длинный", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"перевод строки", "abc\r\nSet-Cookie: x", false},
+		{"управляющий символ", "abc\x00", false},
+		{"табуляция", "abc\tdef", false},
+		{"пробел", "abc def", false},
+		{"не латиница", "запрос-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validRequestID(tt.id); got != tt.want {
+				t.Errorf("validRequestID(%q) = %v, ожидалось %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{"идентификатор клиента сохраняется", "req-42", true},
+		{"без идентификатора создаётся новый", "", false},
+		{"слишком длинный заменяется", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"недопустимые символы заменяются", "req\x7f42", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromContext string
+			router := gin.New()
+			router.Use(RequestID())
+			router.GET("/test", func(ctx *gin.Context) {
+				fromContext = ctx.GetString(RequestIDKey)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got != fromContext {
+				t.Errorf("в заголовке %q, в контексте %q", got, fromContext)
+			}
+			if tt.wantSame {
+				if got != tt.header {
+					t.Errorf("идентификатор %q, ожидался %q", got, tt.header)
+				}
+				return
+			}
+			if _, err := uuid.Parse(got); err != nil {
+				t.Errorf("идентификатор %q, ожидался новый UUID", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Действия с подписками, которые записываются в журнал аудита
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+// Типы исполнителей изменений в журнале аудита
+const (
+	ActorUser      = "user"
+	ActorAPIKey    = "api_key"
+	ActorAnonymous = "anonymous"
+)
+
+// AuditEntry - запись журнала аудита об изменении подписки
+type AuditEntry struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	ServiceName    string     `json:"service_name"`
+	ActorType      string     `json:"actor_type"`
+	ActorID        *uuid.UUID `json:"actor_id,omitempty"`
+	Action         string     `json:"action"`
+	// Before и After - подписка до и после изменения, Before - null для создания
+	Before    json.RawMessage `json:"before" swaggertype:"object"`
+	After     json.RawMessage `json:"after" swaggertype:"object"`
+	RequestID string          `json:"request_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type AuditList struct {
+	Items []*AuditEntry `json:"items"`
+}
+
+// AuditRequest - параметры запроса журнала аудита. Границы периода в формате RFC 3339
+type AuditRequest struct {
+	ActorID     *string `form:"actor_id"`
+	UserID      *string `form:"user_id"`
+	ServiceName *string `form:"service_name"`
+	Action      *string `form:"action" binding:"omitempty,oneof=create update delete restore"`
+	From        *string `form:"from"`
+	To          *string `form:"to"`
+	Limit       int     `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type AuditFilter struct {
+	ActorID     *uuid.UUID
+	UserID      *uuid.UUID
+	ServiceName *string
+	Action      *string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag возвращает сильный тег сущности подписки. Тег включает ID, поскольку по пути
+// /subscriptions/{user_id}/{service_name} в разное время может возвращаться разный период подписки
+func (s *Subscription) ETag() string {
+	return `"` + s.ID.String() + "-" + strconv.Itoa(s.Version) + `"`
+}
+
+// ParseETags разбирает значение заголовка If-Match или If-None-Match в список тегов.
+// Для отсутствующего заголовка возвращается nil
+func ParseETags(header string) []string {
+	if strings.TrimSpace(header) == "" {
+		return nil
+	}
+
+	tags := []string{}
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// MatchETag сообщает, совпадает ли etag с одним из тегов tags или tags содержит "*".
+// При weak слабые теги (W/"...") сравниваются по значению, иначе никогда не совпадают (RFC 9110, 8.8.3.2)
+func MatchETag(tags []string, etag string, weak bool) bool {
+	for _, tag := range tags {
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
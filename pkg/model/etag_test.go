@@ -0,0 +1,62 @@
+package model
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseETags(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", nil},
+		{"  ", nil},
+		{`"a-1"`, []string{`"a-1"`}},
+		{` "a-1" , W/"a-2",,`, []string{`"a-1"`, `W/"a-2"`}},
+		{"*", []string{"*"}},
+		{",", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got := ParseETags(tt.header)
+			if (got == nil) != (tt.want == nil) || !slices.Equal(got, tt.want) {
+				t.Errorf("ParseETags(%q) = %#v, ожидалось %#v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	sub := &Subscription{ID: uuid.MustParse("6a1f2c3d-0000-4000-8000-000000000001"), Version: 2}
+	etag := sub.ETag()
+	if etag != `"6a1f2c3d-0000-4000-8000-000000000001-2"` {
+		t.Fatalf("ETag() = %s", etag)
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		weak bool
+		want bool
+	}{
+		{"совпадение", []string{etag}, false, true},
+		{"один из тегов", []string{`"other"`, etag}, false, true},
+		{"любой тег", []string{"*"}, false, true},
+		{"другая версия", []string{`"6a1f2c3d-0000-4000-8000-000000000001-1"`}, false, false},
+		{"слабый тег при строгом сравнении", []string{"W/" + etag}, false, false},
+		{"слабый тег при слабом сравнении", []string{"W/" + etag}, true, true},
+		{"пустой список", []string{}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchETag(tt.tags, etag, tt.weak); got != tt.want {
+				t.Errorf("MatchETag(%v, weak=%v) = %v, ожидалось %v", tt.tags, tt.weak, got, tt.want)
+			}
+		})
+	}
+}
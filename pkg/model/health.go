@@ -0,0 +1,21 @@
+package model
+
+// Состояния сервиса и отдельных проверок
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// HealthReport - результат проверки готовности сервиса
+type HealthReport struct {
+	Status string        `json:"status" example:"up"`
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck - результат отдельной проверки
+type HealthCheck struct {
+	Name      string  `json:"name" example:"database"`
+	Status    string  `json:"status" example:"up"`
+	LatencyMs float64 `json:"latency_ms" example:"1.25"`
+	Error     string  `json:"error,omitempty"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// IdempotencyRecord - ответ на запрос с заголовком Idempotency-Key, который возвращается при повторе запроса
+type IdempotencyRecord struct {
+	ClientKey string
+	Key       string
+	// SHA-256 метода, адреса и тела запроса
+	RequestHash string
+	// Код ответа, 0 - запрос ещё выполняется
+	StatusCode int
+	Header     map[string]string
+	Body       []byte
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// Completed сообщает, сохранён ли ответ на запрос
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
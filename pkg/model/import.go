@@ -0,0 +1,68 @@
+package model
+
+import "github.com/google/uuid"
+
+// Режимы импорта подписок
+const (
+	// Подписки загружаются, только если все строки верны и не пересекаются с существующими периодами
+	ImportModeAllOrNothing = "all_or_nothing"
+	// Неверные и пересекающиеся строки пропускаются, остальные загружаются
+	ImportModeSkipInvalid = "skip_invalid"
+	// Как skip_invalid, но строка с датой начала существующего периода подписки обновляет этот период
+	ImportModeUpsert = "upsert"
+)
+
+// Форматы файла импорта
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// Результаты импорта строки
+const (
+	ImportStatusCreated = "created"
+	ImportStatusUpdated = "updated"
+	// Строка не прошла проверку
+	ImportStatusInvalid = "invalid"
+	// Период пересекается с существующим периодом подписки или с другой строкой файла
+	ImportStatusConflict = "conflict"
+	// Верная строка не загружена, потому что в режиме all_or_nothing в файле есть ошибки
+	ImportStatusRejected = "rejected"
+)
+
+type ImportRequest struct {
+	Mode string `form:"mode" binding:"omitempty,oneof=all_or_nothing skip_invalid upsert"`
+}
+
+// ImportRow - проверенная строка файла импорта
+type ImportRow struct {
+	// Номер строки в файле, начиная с 1
+	Line         int
+	Subscription *Subscription
+	// Результат загрузки строки, заполняется репозиторием
+	Status   string
+	Conflict *Subscription
+}
+
+// ImportRowResult - результат импорта строки файла
+type ImportRowResult struct {
+	Line     int           `json:"line"`
+	Status   string        `json:"status" enums:"created,updated,invalid,conflict,rejected"`
+	ID       *uuid.UUID    `json:"id,omitempty"`
+	Code     string        `json:"code,omitempty"`
+	Message  string        `json:"message,omitempty"`
+	Errors   []FieldError  `json:"errors,omitempty"`
+	Conflict *Subscription `json:"conflict,omitempty"`
+}
+
+// ImportReport - отчёт об импорте подписок
+type ImportReport struct {
+	Mode string `json:"mode"`
+	// Изменения сохранены. В режиме all_or_nothing false, если в файле есть ошибки
+	Committed bool              `json:"committed"`
+	Total     int               `json:"total"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
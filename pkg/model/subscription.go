@@ -1,22 +1,45 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Subscription struct {
+	ID          uuid.UUID  `json:"id"`
 	ServiceName string     `json:"service_name" binding:"required"`
 	Price       int        `json:"price" binding:"required,min=1"`
 	UserID      uuid.UUID  `json:"user_id" binding:"required"`
 	StartDate   time.Time  `json:"start_date" binding:"required"`
 	EndDate     *time.Time `json:"end_date,omitempty"`
+	// DeletedAt - время удаления, задано только для удалённых подписок
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version увеличивается при каждом изменении подписки
+	Version int `json:"version"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+// Problem - описание ошибки в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Status    int           `json:"status"`
+	Detail    string        `json:"detail,omitempty"`
+	Instance  string        `json:"instance,omitempty"`
+	Code      string        `json:"code"`
+	RequestID string        `json:"request_id,omitempty"`
+	Errors    []FieldError  `json:"errors,omitempty"`
+	Conflict  *Subscription `json:"conflict,omitempty"`
 }
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
 type CreateSubscriptionRequest struct {
 	ServiceName string    `json:"service_name" binding:"required"`
 	Price       int       `json:"price" binding:"required,min=1"`
@@ -25,35 +48,148 @@ type CreateSubscriptionRequest struct {
 	EndDate     *string   `json:"end_date,omitempty"`
 }
 
-type UpdateSubscriptionRequest struct {
-	Price     *int    `json:"price,omitempty"`
-	StartDate *string `json:"start_date,omitempty"`
-	EndDate   *string `json:"end_date,omitempty"`
+// PatchSubscriptionRequest описывает изменение подписки в формате JSON Merge Patch (RFC 7396):
+// отсутствующие поля не изменяются, а поле со значением null очищается
+type PatchSubscriptionRequest struct {
+	Price     Optional[int]    `json:"price" swaggertype:"integer"`
+	StartDate Optional[string] `json:"start_date" swaggertype:"string"`
+	EndDate   Optional[string] `json:"end_date" swaggertype:"string"`
 }
 
-type UpdateSubscription struct {
-	ServiceName string     `json:"service_name" binding:"required"`
-	UserID      uuid.UUID  `json:"user_id" binding:"required"`
-	Price       *int       `json:"price,omitempty"`
-	StartDate   *time.Time `json:"start_date,omitempty"`
-	EndDate     *time.Time `json:"end_date,omitempty"`
+// Optional хранит значение поля JSON с признаками его наличия в документе и явного null
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	// UnmarshalJSON вызывается только для присутствующих в документе полей
+	o.Set = true
+	if string(data) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// Поля сортировки списка подписок
+const (
+	SortByStartDate   = "start_date"
+	SortByPrice       = "price"
+	SortByServiceName = "service_name"
+)
+
+// Статусы подписки для фильтрации списка
+const (
+	StatusActive = "active"
+	StatusEnded  = "ended"
+)
+
+type ListRequest struct {
+	UserID         *string `form:"user_id"`
+	ServiceName    *string `form:"service_name"`
+	ServicePrefix  *string `form:"service_prefix"`
+	PriceMin       *int    `form:"price_min" binding:"omitempty,min=1"`
+	PriceMax       *int    `form:"price_max" binding:"omitempty,min=1"`
+	ActiveOn       *string `form:"active_on"`
+	Status         *string `form:"status" binding:"omitempty,oneof=active ended"`
+	Sort           string  `form:"sort"`
+	Limit          int     `form:"limit" binding:"omitempty,min=1,max=500"`
+	Cursor         string  `form:"cursor"`
+	IncludeTotal   bool    `form:"include_total"`
+	IncludeDeleted bool    `form:"include_deleted"`
+}
+
+type ListFilter struct {
+	UserID         *uuid.UUID
+	ServiceName    *string
+	ServicePrefix  *string
+	PriceMin       *int
+	PriceMax       *int
+	ActiveOn       *time.Time
+	Status         *string
+	SortField      string
+	SortDesc       bool
+	Limit          int
+	After          *ListCursor
+	IncludeDeleted bool
+}
+
+// ListCursor - позиция в списке подписок для постраничной выборки по ключу:
+// значение поля сортировки и ID последней подписки предыдущей страницы
+type ListCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+type SubscriptionList struct {
+	Items      []*Subscription `json:"items"`
+	NextCursor *string         `json:"next_cursor,omitempty"`
+	TotalCount *int            `json:"total_count,omitempty"`
+}
+
+type SubscriptionPeriod struct {
+	UserID      uuid.UUID
+	ServiceName string
+	StartDate   time.Time
+	EndDate     *time.Time
+	ExcludeID   *uuid.UUID
 }
 
 type TotalRequest struct {
-	UserID      *string `form:"user_id"`
-	ServiceName *string `form:"service_name"`
-	StartPeriod string  `form:"start_period" binding:"required"`
-	EndPeriod   string  `form:"end_period" binding:"required"`
+	UserID         *string `form:"user_id"`
+	ServiceName    *string `form:"service_name"`
+	StartPeriod    string  `form:"start_period" binding:"required"`
+	EndPeriod      string  `form:"end_period" binding:"required"`
+	IncludeDeleted bool    `form:"include_deleted"`
 }
 
 type TotalSubscription struct {
-	UserID      *uuid.UUID `form:"user_id"`
-	ServiceName *string    `form:"service_name"`
-	StartPeriod time.Time  `form:"start_period" binding:"required"`
-	EndPeriod   time.Time  `form:"end_period" binding:"required"`
+	UserID         *uuid.UUID `form:"user_id"`
+	ServiceName    *string    `form:"service_name"`
+	StartPeriod    time.Time  `form:"start_period" binding:"required"`
+	EndPeriod      time.Time  `form:"end_period" binding:"required"`
+	IncludeDeleted bool
 }
 
 type TotalResponse struct {
 	TotalCost int `json:"total_cost"`
 	Count     int `json:"count"`
+	Months    int `json:"months"`
+}
+
+// ServiceStats - действующие в текущем месяце подписки на сервис
+type ServiceStats struct {
+	ServiceName  string
+	ActiveCount  int
+	MonthlySpend int
+}
+
+// Допустимые значения параметра group_by для детализации расходов
+const (
+	GroupByMonth       = "month"
+	GroupByServiceName = "service_name"
+	GroupByUserID      = "user_id"
+)
+
+type BreakdownRequest struct {
+	TotalRequest
+	GroupBy []string `form:"group_by"`
+}
+
+type BreakdownSubscription struct {
+	TotalSubscription
+	GroupByMonth       bool
+	GroupByServiceName bool
+	GroupByUserID      bool
+}
+
+type BreakdownItem struct {
+	Month       *string    `json:"month,omitempty"`
+	ServiceName *string    `json:"service_name,omitempty"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	TotalCost   int        `json:"total_cost"`
+	Count       int        `json:"count"`
 }
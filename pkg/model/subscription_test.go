@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPatchSubscriptionRequestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantPrice Optional[int]
+		wantEnd   Optional[string]
+	}{
+		{"пустой документ", `{}`, Optional[int]{}, Optional[string]{}},
+		{"новое значение", `{"price":500}`, Optional[int]{Set: true, Value: 500}, Optional[string]{}},
+		{"очистка поля", `{"end_date":null}`, Optional[int]{}, Optional[string]{Set: true, Null: true}},
+		{"оба поля", `{"price":1,"end_date":"12-2025"}`, Optional[int]{Set: true, Value: 1}, Optional[string]{Set: true, Value: "12-2025"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req PatchSubscriptionRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if req.Price != tt.wantPrice {
+				t.Errorf("price = %+v, ожидалось %+v", req.Price, tt.wantPrice)
+			}
+			if req.EndDate != tt.wantEnd {
+				t.Errorf("end_date = %+v, ожидалось %+v", req.EndDate, tt.wantEnd)
+			}
+			if req.StartDate.Set {
+				t.Errorf("start_date = %+v, ожидалось отсутствие поля", req.StartDate)
+			}
+		})
+	}
+}
+
+func TestOptionalRejectsWrongType(t *testing.T) {
+	var req PatchSubscriptionRequest
+	if err := json.Unmarshal([]byte(`{"price":"500"}`), &req); err == nil {
+		t.Fatal("строка в числовом поле принята без ошибки")
+	}
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Как часто удаляются полностью пополненные корзины
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// fullAt - момент полного пополнения корзины, после которого её можно удалить
+	fullAt time.Time
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore создаёт хранилище корзин в памяти процесса
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (s *memoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	rate := limit.rate()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	b.fullAt = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep удаляет корзины, которые уже полностью пополнились: новая корзина будет такой же
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{buckets: make(map[string]*bucket), lastSweep: now, now: func() time.Time { return now }}
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	steps := []struct {
+		name           string
+		advance        time.Duration
+		key            string
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{"первый запрос", 0, "a", true, 2, 0},
+		{"второй запрос", 0, "a", true, 1, 0},
+		{"третий запрос", 0, "a", true, 0, 0},
+		{"корзина пуста", 0, "a", false, 0, time.Second},
+		{"другой клиент", 0, "b", true, 2, 0},
+		{"корзина частично пополнилась", 500 * time.Millisecond, "a", false, 0, 500 * time.Millisecond},
+		{"пополнился один запрос", 500 * time.Millisecond, "a", true, 0, 0},
+		{"корзина полностью пополнилась", time.Minute, "a", true, 2, 0},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		result, err := store.Take(context.Background(), step.key, limit)
+		if err != nil {
+			t.Fatalf("%s: Take() error = %v", step.name, err)
+		}
+		if result.Allowed != step.wantAllowed || result.Remaining != step.wantRemaining || result.RetryAfter != step.wantRetryAfter {
+			t.Errorf("%s: Take() = %+v, ожидалось allowed=%v remaining=%d retry_after=%v",
+				step.name, result, step.wantAllowed, step.wantRemaining, step.wantRetryAfter)
+		}
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{buckets: make(map[string]*bucket), lastSweep: now, now: func() time.Time { return now }}
+
+	store.Take(context.Background(), "short", Limit{Requests: 10, Period: time.Second})
+	store.Take(context.Background(), "long", Limit{Requests: 10, Period: time.Hour})
+
+	now = now.Add(sweepInterval)
+	store.Take(context.Background(), "new", Limit{Requests: 10, Period: time.Second})
+
+	if _, ok := store.buckets["short"]; ok {
+		t.Error("пополнившаяся корзина не удалена")
+	}
+	if _, ok := store.buckets["long"]; !ok {
+		t.Error("удалена корзина, которая ещё пополняется")
+	}
+}
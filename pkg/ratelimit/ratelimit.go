@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit - ограничение по алгоритму token bucket: в корзине помещается Requests запросов,
+// и она полностью пополняется за Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// rate возвращает скорость пополнения корзины в запросах в секунду
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result - результат попытки выполнить запрос
+type Result struct {
+	Allowed bool
+	// Remaining - сколько запросов ещё можно выполнить без ожидания
+	Remaining int
+	// Reset - время до полного пополнения корзины
+	Reset time.Duration
+	// RetryAfter - время до появления свободного запроса, если запрос отклонён
+	RetryAfter time.Duration
+}
+
+// Store хранит корзины клиентов. Для нескольких экземпляров сервиса
+// нужна реализация с общим хранилищем (например, Redis)
+type Store interface {
+	// Take забирает из корзины key один запрос, если он есть
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *model.APIKey, keyHash string) error
+	GetByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+	List(ctx context.Context) ([]*model.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+const apiKeyColumns = "id, name, prefix, scopes, created_at, last_used_at, revoked_at"
+
+type apiKeyRepo struct {
+	pool   *pgxpool.Pool
+	logger *logrus.Logger
+}
+
+func NewAPIKeyRepo(pool *pgxpool.Pool, logger *logrus.Logger) APIKeyRepository {
+	return &apiKeyRepo{pool: pool, logger: logger}
+}
+
+func (r *apiKeyRepo) Create(ctx context.Context, key *model.APIKey, keyHash string) error {
+	query := `INSERT INTO api_keys (name, prefix, key_hash, scopes)
+              VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+
+	err := r.pool.QueryRow(ctx, query, key.Name, key.Prefix, keyHash, key.Scopes).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при создании записи в таблице api_keys")
+		return err
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepo) GetByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash = $1"
+
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, query, keyHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperr.Wrap(apperr.ErrNotFound, apperr.CodeAPIKeyNotFound, "Ключ API не найден", err)
+	}
+	if err != nil {
+		logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при поиске ключа API")
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepo) List(ctx context.Context) ([]*model.APIKey, error) {
+	rows, err := r.pool.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Revoke отзывает ключ. Повторный отзыв не меняет время отзыва
+func (r *apiKeyRepo) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1"
+
+	result, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return apperr.NotFound(apperr.CodeAPIKeyNotFound, "Ключ API не найден")
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepo) TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := r.pool.Exec(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", id, usedAt)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при обновлении времени использования ключа API")
+	}
+	return err
+}
+
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var key model.APIKey
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditRepository interface {
+	List(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEntry, error)
+}
+
+const auditColumns = "id, subscription_id, user_id, service_name, actor_type, actor_id, action, before, after, request_id, created_at"
+
+type auditRepo struct {
+	pool   *pgxpool.Pool
+	logger *logrus.Logger
+}
+
+func NewAuditRepo(pool *pgxpool.Pool, logger *logrus.Logger) AuditRepository {
+	return &auditRepo{pool: pool, logger: logger}
+}
+
+// List возвращает записи журнала аудита, подходящие под фильтры, начиная с последних
+func (r *auditRepo) List(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEntry, error) {
+	where, args := auditConditions(filter)
+	args = append(args, filter.Limit)
+
+	query := `SELECT ` + auditColumns + `
+              FROM audit_log WHERE ` + where +
+		fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при выполнении запроса журнала аудита")
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*model.AuditEntry{}
+	for rows.Next() {
+		var entry model.AuditEntry
+		var requestID *string
+		err := rows.Scan(&entry.ID, &entry.SubscriptionID, &entry.UserID, &entry.ServiceName, &entry.ActorType,
+			&entry.ActorID, &entry.Action, &entry.Before, &entry.After, &requestID, &entry.CreatedAt)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при сканировании строки результата запроса")
+			return nil, err
+		}
+		if requestID != nil {
+			entry.RequestID = *requestID
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при чтении результата запроса")
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// auditConditions формирует условие WHERE и его аргументы по фильтрам журнала аудита.
+// Фильтр по пользователю и сервису находит записи и по владельцу и сервису подписки до изменения,
+// чтобы перенос подписки к другому пользователю или сервису оставался в истории прежней подписки
+func auditConditions(filter *model.AuditFilter) (string, []interface{}) {
+	conditions := []string{"1=1"}
+	args := []interface{}{}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorID != nil {
+		add("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.UserID != nil || filter.ServiceName != nil {
+		var current, previous []string
+		if filter.UserID != nil {
+			args = append(args, *filter.UserID)
+			current = append(current, fmt.Sprintf("user_id = $%d", len(args)))
+			previous = append(previous, fmt.Sprintf("previous_user_id = $%d", len(args)))
+		}
+		if filter.ServiceName != nil {
+			args = append(args, *filter.ServiceName)
+			current = append(current, fmt.Sprintf("service_name = $%d", len(args)))
+			previous = append(previous, fmt.Sprintf("previous_service_name = $%d", len(args)))
+		}
+		conditions = append(conditions, fmt.Sprintf("((%s) OR (%s))",
+			strings.Join(current, " AND "), strings.Join(previous, " AND ")))
+	}
+	if filter.Action != nil {
+		add("action = $%d", *filter.Action)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// insertAudit записывает в журнал аудита изменение подписки в транзакции tx.
+// Исполнитель и идентификатор запроса берутся из ctx
+func insertAudit(ctx context.Context, tx pgx.Tx, action string, before, after *model.Subscription) error {
+	sub := after
+	if sub == nil {
+		sub = before
+	}
+
+	actorType, actorID := auditActor(ctx)
+	var requestID *string
+	if id := logging.RequestID(ctx); id != "" {
+		requestID = &id
+	}
+
+	previousUserID, previousServiceName := previousKey(before, after)
+
+	query := `INSERT INTO audit_log (subscription_id, user_id, service_name, previous_user_id, previous_service_name,
+                  actor_type, actor_id, action, before, after, request_id)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := tx.Exec(ctx, query, sub.ID, sub.UserID, sub.ServiceName, previousUserID, previousServiceName,
+		actorType, actorID, action, before, after, requestID)
+	return err
+}
+
+// previousKey возвращает пользователя и сервис подписки до изменения, если изменение их поменяло
+func previousKey(before, after *model.Subscription) (*uuid.UUID, *string) {
+	if before == nil || after == nil || (before.UserID == after.UserID && before.ServiceName == after.ServiceName) {
+		return nil, nil
+	}
+	return &before.UserID, &before.ServiceName
+}
+
+// auditActor определяет исполнителя изменения по пользователю запроса
+func auditActor(ctx context.Context) (string, *uuid.UUID) {
+	principal, ok := auth.FromContext(ctx)
+	switch {
+	case !ok:
+		return model.ActorAnonymous, nil
+	case principal.IsService():
+		return model.ActorAPIKey, principal.APIKeyID
+	}
+	return model.ActorUser, &principal.UserID
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/model"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAuditActor(t *testing.T) {
+	userID, keyID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantType  string
+		wantActor *uuid.UUID
+	}{
+		{"без аутентификации", context.Background(), model.ActorAnonymous, nil},
+		{"пользователь", auth.WithPrincipal(context.Background(), &auth.Principal{UserID: userID}), model.ActorUser, &userID},
+		{"ключ API", auth.WithPrincipal(context.Background(), &auth.Principal{APIKeyID: &keyID}), model.ActorAPIKey, &keyID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actorType, actorID := auditActor(tt.ctx)
+			if actorType != tt.wantType {
+				t.Errorf("тип исполнителя %q, ожидался %q", actorType, tt.wantType)
+			}
+			if (actorID == nil) != (tt.wantActor == nil) || (actorID != nil && *actorID != *tt.wantActor) {
+				t.Errorf("исполнитель %v, ожидался %v", actorID, tt.wantActor)
+			}
+		})
+	}
+}
+
+func TestPreviousKey(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+	sub := &model.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Yandex Plus", Price: 400}
+
+	moved := *sub
+	moved.UserID = otherID
+	renamed := *sub
+	renamed.ServiceName = "Kinopoisk"
+	repriced := *sub
+	repriced.Price = 500
+
+	tests := []struct {
+		name        string
+		before      *model.Subscription
+		after       *model.Subscription
+		wantUser    *uuid.UUID
+		wantService *string
+	}{
+		{"создание", nil, sub, nil, nil},
+		{"изменение цены", sub, &repriced, nil, nil},
+		{"перенос к другому пользователю", sub, &moved, &userID, &sub.ServiceName},
+		{"смена сервиса", sub, &renamed, &userID, &sub.ServiceName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, service := previousKey(tt.before, tt.after)
+			if (user == nil) != (tt.wantUser == nil) || (user != nil && *user != *tt.wantUser) {
+				t.Errorf("прежний пользователь %v, ожидался %v", user, tt.wantUser)
+			}
+			if (service == nil) != (tt.wantService == nil) || (service != nil && *service != *tt.wantService) {
+				t.Errorf("прежний сервис %v, ожидался %v", service, tt.wantService)
+			}
+		})
+	}
+}
+
+func TestAuditConditions(t *testing.T) {
+	userID := uuid.New()
+	serviceName := "Yandex Plus"
+	action := model.AuditActionUpdate
+
+	tests := []struct {
+		name     string
+		filter   *model.AuditFilter
+		want     string
+		wantArgs int
+	}{
+		{"без фильтров", &model.AuditFilter{}, "1=1", 0},
+		{"история подписки", &model.AuditFilter{UserID: &userID, ServiceName: &serviceName},
+			"1=1 AND ((user_id = $1 AND service_name = $2) OR (previous_user_id = $1 AND previous_service_name = $2))", 2},
+		{"пользователь и действие", &model.AuditFilter{UserID: &userID, Action: &action},
+			"1=1 AND ((user_id = $1) OR (previous_user_id = $1)) AND action = $2", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := auditConditions(tt.filter)
+			if where != tt.want {
+				t.Errorf("условие %q, ожидалось %q", where, tt.want)
+			}
+			if len(args) != tt.wantArgs {
+				t.Errorf("аргументы %v, ожидалось %d", args, tt.wantArgs)
+			}
+		})
+	}
+}
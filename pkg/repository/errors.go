@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"errors"
+	"subscription_service/pkg/apperr"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды ошибок PostgreSQL, которые переводятся в ошибки предметной области
+const (
+	uniqueViolationCode    = "23505"
+	exclusionViolationCode = "23P01"
+	checkViolationCode     = "23514"
+	dataExceptionCode      = "22000"
+)
+
+// translateError переводит ошибки pgx и PostgreSQL в ошибки пакета apperr.
+// Остальные ошибки возвращаются без изменений и считаются внутренними
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperr.Wrap(apperr.ErrNotFound, apperr.CodeSubscriptionNotFound, "Подписка не найдена", err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolationCode, exclusionViolationCode:
+			return apperr.Wrap(apperr.ErrConflict, apperr.CodeSubscriptionExists, "Период подписки пересекается с существующим периодом этой подписки", err)
+		case checkViolationCode, dataExceptionCode:
+			return apperr.Wrap(apperr.ErrUnprocessable, apperr.CodeConstraintViolation, "Данные подписки нарушают ограничения хранилища", err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"subscription_service/pkg/apperr"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestTranslateError(t *testing.T) {
+	internal := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+		wantCode string
+	}{
+		{"нет ошибки", nil, nil, ""},
+		{"нет строк", pgx.ErrNoRows, apperr.ErrNotFound, apperr.CodeSubscriptionNotFound},
+		{"нарушение уникальности", &pgconn.PgError{Code: uniqueViolationCode}, apperr.ErrConflict, apperr.CodeSubscriptionExists},
+		{"нарушение ограничения исключения", &pgconn.PgError{Code: exclusionViolationCode}, apperr.ErrConflict, apperr.CodeSubscriptionExists},
+		{"обёрнутое нарушение ограничения исключения", fmt.Errorf("insert: %w", &pgconn.PgError{Code: exclusionViolationCode}), apperr.ErrConflict, apperr.CodeSubscriptionExists},
+		{"нарушение проверки", &pgconn.PgError{Code: checkViolationCode}, apperr.ErrUnprocessable, apperr.CodeConstraintViolation},
+		{"неверный диапазон дат", &pgconn.PgError{Code: dataExceptionCode}, apperr.ErrUnprocessable, apperr.CodeConstraintViolation},
+		{"внутренняя ошибка", internal, internal, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translateError(tt.err)
+			if tt.wantKind == nil {
+				if err != nil {
+					t.Fatalf("translateError() = %v, ожидался nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("translateError() = %v, ожидалась %v", err, tt.wantKind)
+			}
+			if appErr, ok := apperr.As(err); ok && appErr.Code != tt.wantCode {
+				t.Errorf("код ошибки %q, ожидался %q", appErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// HealthRepository - проверки доступности хранилища
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+type healthRepo struct {
+	pool   *pgxpool.Pool
+	logger *logrus.Logger
+}
+
+func NewHealthRepo(pool *pgxpool.Pool, logger *logrus.Logger) HealthRepository {
+	return &healthRepo{pool: pool, logger: logger}
+}
+
+func (r *healthRepo) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+// MigrationVersion возвращает применённую версию миграций из таблицы golang-migrate
+func (r *healthRepo) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := r.pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type IdempotencyRepository interface {
+	// Reserve сохраняет ключ запроса, если его ещё нет, срок его хранения истёк или запрос с ним
+	// начал выполняться раньше lockedBefore и не завершился (например, экземпляр сервиса упал), и возвращает nil.
+	// Если ключ уже сохранён, возвращается существующая запись
+	Reserve(ctx context.Context, record *model.IdempotencyRecord, lockedBefore time.Time) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *model.IdempotencyRecord) error
+	Delete(ctx context.Context, clientKey, key string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+const idempotencyColumns = "client_key, idempotency_key, request_hash, status_code, headers, body, created_at, expires_at"
+
+// Количество попыток сохранить ключ, если запись удаляется между вставкой и чтением
+const idempotencyReserveAttempts = 3
+
+type idempotencyRepo struct {
+	pool   *pgxpool.Pool
+	logger *logrus.Logger
+}
+
+func NewIdempotencyRepo(pool *pgxpool.Pool, logger *logrus.Logger) IdempotencyRepository {
+	return &idempotencyRepo{pool: pool, logger: logger}
+}
+
+func (r *idempotencyRepo) Reserve(ctx context.Context, record *model.IdempotencyRecord, lockedBefore time.Time) (*model.IdempotencyRecord, error) {
+	query := `INSERT INTO idempotency_keys (client_key, idempotency_key, request_hash, expires_at)
+              VALUES ($1, $2, $3, $4)
+              ON CONFLICT (client_key, idempotency_key) DO UPDATE
+              SET request_hash = EXCLUDED.request_hash, status_code = NULL, headers = NULL, body = NULL,
+                  created_at = now(), expires_at = EXCLUDED.expires_at
+              WHERE idempotency_keys.expires_at <= now()
+                 OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at <= $5)`
+
+	for range idempotencyReserveAttempts {
+		tag, err := r.pool.Exec(ctx, query, record.ClientKey, record.Key, record.RequestHash, record.ExpiresAt, lockedBefore)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при сохранении ключа идемпотентности")
+			return nil, err
+		}
+		if tag.RowsAffected() == 1 {
+			return nil, nil
+		}
+
+		existing, err := r.get(ctx, record.ClientKey, record.Key)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при чтении ключа идемпотентности")
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	return nil, errors.New("не удалось сохранить ключ идемпотентности")
+}
+
+func (r *idempotencyRepo) get(ctx context.Context, clientKey, key string) (*model.IdempotencyRecord, error) {
+	query := "SELECT " + idempotencyColumns + " FROM idempotency_keys WHERE client_key = $1 AND idempotency_key = $2"
+
+	var record model.IdempotencyRecord
+	var statusCode *int
+	err := r.pool.QueryRow(ctx, query, clientKey, key).Scan(&record.ClientKey, &record.Key, &record.RequestHash,
+		&statusCode, &record.Header, &record.Body, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != nil {
+		record.StatusCode = *statusCode
+	}
+
+	return &record, nil
+}
+
+// Complete сохраняет ответ на запрос
+func (r *idempotencyRepo) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	query := `UPDATE idempotency_keys SET status_code = $3, headers = $4, body = $5
+              WHERE client_key = $1 AND idempotency_key = $2`
+
+	_, err := r.pool.Exec(ctx, query, record.ClientKey, record.Key, record.StatusCode, record.Header, record.Body)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при сохранении ответа по ключу идемпотентности")
+		return err
+	}
+
+	return nil
+}
+
+// Delete освобождает ключ, чтобы запрос с ним можно было выполнить заново
+func (r *idempotencyRepo) Delete(ctx context.Context, clientKey, key string) error {
+	_, err := r.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE client_key = $1 AND idempotency_key = $2", clientKey, key)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).WithError(err).Error("Ошибка при удалении ключа идемпотентности")
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpired удаляет ключи, срок хранения которых истёк до before
+func (r *idempotencyRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
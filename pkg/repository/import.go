@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Столбцы подписок с псевдонимом таблицы s для запросов с соединением
+const importSubscriptionColumns = "s.id, s.service_name, s.price, s.user_id, s.start_date, s.end_date, s.deleted_at, s.version"
+
+// Строки импорта в виде массивов для unnest, i(line, user_id, service_name, start_date, end_date)
+const importRowsTable = `unnest($1::integer[], $2::uuid[], $3::text[], $4::date[], $5::date[])
+              AS i(line, user_id, service_name, start_date, end_date)`
+
+// errImportRejected отменяет транзакцию импорта в режиме all_or_nothing, если есть пересекающиеся строки
+var errImportRejected = errors.New("import rejected")
+
+// auditRecord - изменение подписки для записи в журнал аудита
+type auditRecord struct {
+	action        string
+	before, after *model.Subscription
+}
+
+// Import загружает подписки из rows в одной транзакции: новые периоды копируются через COPY,
+// а все изменения записываются в журнал аудита. Строки, пересекающиеся с существующими периодами,
+// получают статус conflict, в режиме all_or_nothing при наличии таких строк ничего не сохраняется.
+// В режиме upsert строка с датой начала существующего периода подписки обновляет его стоимость и дату окончания
+func (r *repo) Import(ctx context.Context, rows []*model.ImportRow, mode string) error {
+	upsert := mode == model.ImportModeUpsert
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		conflicts, err := findImportConflicts(ctx, tx, rows, upsert)
+		if err != nil {
+			return err
+		}
+
+		pending := make([]*model.ImportRow, 0, len(rows))
+		for _, row := range rows {
+			if conflict, ok := conflicts[row.Line]; ok {
+				row.Status, row.Conflict = model.ImportStatusConflict, conflict
+				continue
+			}
+			pending = append(pending, row)
+		}
+		if len(conflicts) > 0 && mode == model.ImportModeAllOrNothing {
+			return errImportRejected
+		}
+
+		var audits []auditRecord
+		if upsert {
+			pending, audits, err = updateImported(ctx, tx, pending)
+			if err != nil {
+				return err
+			}
+		}
+
+		created, err := copyImported(ctx, tx, pending)
+		if err != nil {
+			return err
+		}
+		audits = append(audits, created...)
+
+		return copyAudit(ctx, tx, audits)
+	})
+	if errors.Is(err, errImportRejected) {
+		return nil
+	}
+	if err != nil {
+		r.log(ctx).WithError(err).Error("Ошибка при импорте подписок")
+		return translateError(err)
+	}
+
+	r.log(ctx).WithFields(logrus.Fields{
+		"mode": mode,
+		"rows": len(rows),
+	}).Info("Импорт подписок выполнен")
+
+	return nil
+}
+
+// importColumns возвращает значения строк импорта по столбцам для importRowsTable
+func importColumns(rows []*model.ImportRow) []any {
+	lines := make([]int, len(rows))
+	userIDs := make([]uuid.UUID, len(rows))
+	serviceNames := make([]string, len(rows))
+	startDates := make([]time.Time, len(rows))
+	endDates := make([]*time.Time, len(rows))
+	for i, row := range rows {
+		lines[i] = row.Line
+		userIDs[i] = row.Subscription.UserID
+		serviceNames[i] = row.Subscription.ServiceName
+		startDates[i] = row.Subscription.StartDate
+		endDates[i] = row.Subscription.EndDate
+	}
+	return []any{lines, userIDs, serviceNames, startDates, endDates}
+}
+
+// findImportConflicts возвращает по номерам строк существующие периоды, с которыми они пересекаются.
+// В режиме upsert период с той же датой начала не считается пересечением, его обновит строка
+func findImportConflicts(ctx context.Context, tx pgx.Tx, rows []*model.ImportRow, upsert bool) (map[int]*model.Subscription, error) {
+	query := `SELECT DISTINCT ON (i.line) i.line, ` + importSubscriptionColumns + `
+              FROM ` + importRowsTable + `
+              JOIN subscriptions s ON s.user_id = i.user_id AND s.service_name = i.service_name AND s.deleted_at IS NULL
+                AND daterange(s.start_date, s.end_date, '[]') && daterange(i.start_date, i.end_date, '[]')
+                AND NOT ($6 AND s.start_date = i.start_date)
+              ORDER BY i.line, s.start_date`
+
+	return queryLineSubscriptions(ctx, tx, query, append(importColumns(rows), upsert)...)
+}
+
+// queryLineSubscriptions выполняет запрос, возвращающий номер строки импорта и подписку
+func queryLineSubscriptions(ctx context.Context, tx pgx.Tx, query string, args ...any) (map[int]*model.Subscription, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]*model.Subscription)
+	for rows.Next() {
+		var line int
+		var sub model.Subscription
+		err := rows.Scan(&line, &sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.DeletedAt, &sub.Version)
+		if err != nil {
+			return nil, err
+		}
+		result[line] = &sub
+	}
+
+	return result, rows.Err()
+}
+
+// updateImported обновляет существующие периоды с той же датой начала, что и строки импорта,
+// и возвращает строки, для которых таких периодов нет
+func updateImported(ctx context.Context, tx pgx.Tx, rows []*model.ImportRow) ([]*model.ImportRow, []auditRecord, error) {
+	selectQuery := `SELECT i.line, ` + importSubscriptionColumns + `
+              FROM ` + importRowsTable + `
+              JOIN subscriptions s ON s.user_id = i.user_id AND s.service_name = i.service_name
+                AND s.start_date = i.start_date AND s.deleted_at IS NULL
+              FOR UPDATE OF s`
+	updateQuery := `UPDATE subscriptions s SET price = u.price, end_date = u.end_date, version = s.version + 1
+              FROM unnest($1::uuid[], $2::integer[], $3::date[]) AS u(id, price, end_date)
+              WHERE s.id = u.id RETURNING ` + importSubscriptionColumns
+
+	existing, err := queryLineSubscriptions(ctx, tx, selectQuery, importColumns(rows)...)
+	if err != nil || len(existing) == 0 {
+		return rows, nil, err
+	}
+
+	var ids []uuid.UUID
+	var prices []int
+	var endDates []*time.Time
+	pending := make([]*model.ImportRow, 0, len(rows)-len(existing))
+	for _, row := range rows {
+		before, ok := existing[row.Line]
+		if !ok {
+			pending = append(pending, row)
+			continue
+		}
+		ids = append(ids, before.ID)
+		prices = append(prices, row.Subscription.Price)
+		endDates = append(endDates, row.Subscription.EndDate)
+	}
+
+	result, err := tx.Query(ctx, updateQuery, ids, prices, endDates)
+	if err != nil {
+		return nil, nil, err
+	}
+	updated, err := pgx.CollectRows(result, func(row pgx.CollectableRow) (*model.Subscription, error) {
+		return scanSubscription(row)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uuid.UUID]*model.Subscription, len(updated))
+	for _, sub := range updated {
+		byID[sub.ID] = sub
+	}
+
+	audits := make([]auditRecord, 0, len(existing))
+	for _, row := range rows {
+		before, ok := existing[row.Line]
+		if !ok {
+			continue
+		}
+		row.Subscription, row.Status = byID[before.ID], model.ImportStatusUpdated
+		audits = append(audits, auditRecord{action: model.AuditActionUpdate, before: before, after: row.Subscription})
+	}
+
+	return pending, audits, nil
+}
+
+// copyImported копирует новые периоды подписок через COPY
+func copyImported(ctx context.Context, tx pgx.Tx, rows []*model.ImportRow) ([]auditRecord, error) {
+	audits := make([]auditRecord, len(rows))
+	source := make([][]any, len(rows))
+	for i, row := range rows {
+		sub := row.Subscription
+		sub.ID, sub.Version = uuid.New(), 1
+		source[i] = []any{sub.ID, sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate}
+
+		row.Status = model.ImportStatusCreated
+		audits[i] = auditRecord{action: model.AuditActionCreate, after: sub}
+	}
+
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"subscriptions"},
+		[]string{"id", "service_name", "price", "user_id", "start_date", "end_date"}, pgx.CopyFromRows(source))
+	return audits, err
+}
+
+// copyAudit записывает изменения в журнал аудита через COPY, как insertAudit
+func copyAudit(ctx context.Context, tx pgx.Tx, audits []auditRecord) error {
+	actorType, actorID := auditActor(ctx)
+	var requestID *string
+	if id := logging.RequestID(ctx); id != "" {
+		requestID = &id
+	}
+
+	source := make([][]any, len(audits))
+	for i, audit := range audits {
+		var before any
+		if audit.before != nil {
+			before = audit.before
+		}
+		sub := audit.after
+		source[i] = []any{sub.ID, sub.UserID, sub.ServiceName, actorType, actorID, audit.action, before, audit.after, requestID}
+	}
+
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"audit_log"},
+		[]string{"subscription_id", "user_id", "service_name", "actor_type", "actor_id", "action", "before", "after", "request_id"},
+		pgx.CopyFromRows(source))
+	return err
+}
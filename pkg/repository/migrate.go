@@ -1,14 +1,20 @@
 package repository
 
 import (
+	"errors"
+	"os"
+
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
+const migrationsURL = "file://migrations"
+
 func MigrateDB(connString string) error {
 	m, err := migrate.New(
-		"file://migrations",
+		migrationsURL,
 		connString)
 	if err != nil {
 		return err
@@ -19,3 +25,28 @@ func MigrateDB(connString string) error {
 	}
 	return nil
 }
+
+// LatestMigrationVersion возвращает версию последней миграции в каталоге migrations
+func LatestMigrationVersion() (uint, error) {
+	src, err := source.Open(migrationsURL)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
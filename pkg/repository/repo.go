@@ -2,24 +2,41 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/logging"
 	"subscription_service/pkg/model"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
 
 type Repository interface {
-	List(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]*model.Subscription, error)
+	List(ctx context.Context, filter *model.ListFilter) ([]*model.Subscription, error)
+	Count(ctx context.Context, filter *model.ListFilter) (int, error)
 	Create(ctx context.Context, sub *model.Subscription) error
-	Get(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error)
-	Update(ctx context.Context, updSub *model.UpdateSubscription) (*model.Subscription, error)
-	Delete(ctx context.Context, userID *uuid.UUID, serviceName *string) error
+	Get(ctx context.Context, userID *uuid.UUID, serviceName *string, includeDeleted bool) (*model.Subscription, error)
+	GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.Subscription, error)
+	GetDeleted(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error)
+	FindOverlapping(ctx context.Context, period *model.SubscriptionPeriod) (*model.Subscription, error)
+	Update(ctx context.Context, sub *model.Subscription) (*model.Subscription, error)
+	Delete(ctx context.Context, id uuid.UUID, version int) error
+	Restore(ctx context.Context, id uuid.UUID, version int) (*model.Subscription, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	Import(ctx context.Context, rows []*model.ImportRow, mode string) error
 	GetTotal(ctx context.Context, req *model.TotalSubscription) (*model.TotalResponse, error)
+	GetTotalBreakdown(ctx context.Context, req *model.BreakdownSubscription) ([]*model.BreakdownItem, error)
+	ServiceStats(ctx context.Context) ([]*model.ServiceStats, error)
 }
 
+const subscriptionColumns = "id, service_name, price, user_id, start_date, end_date, deleted_at, version"
+
 type repo struct {
 	pool   *pgxpool.Pool
 	logger *logrus.Logger
@@ -29,71 +46,153 @@ func NewSubRepo(pool *pgxpool.Pool, logger *logrus.Logger) Repository {
 	return &repo{pool: pool, logger: logger}
 }
 
-func (r *repo) List(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]*model.Subscription, error) {
-	query := `SELECT service_name, price, user_id, start_date, end_date 
-              FROM subscriptions WHERE 1=1`
-	args := []interface{}{}
-	argCount := 1
+// log возвращает логгер с полями запроса из ctx
+func (r *repo) log(ctx context.Context) *logrus.Entry {
+	return logging.FromContext(ctx, r.logger)
+}
 
-	if userID != nil {
-		query += fmt.Sprintf(" AND user_id = $%d", argCount)
-		args = append(args, *userID)
-		argCount++
+// Столбцы, по которым допускается сортировка списка, и приведение значения курсора к их типу
+var sortColumns = map[string]string{
+	model.SortByStartDate:   "start_date::date",
+	model.SortByPrice:       "price::integer",
+	model.SortByServiceName: "service_name::text",
+}
+
+func (r *repo) List(ctx context.Context, filter *model.ListFilter) ([]*model.Subscription, error) {
+	where, args := listConditions(filter)
+	argCount := len(args) + 1
+
+	column, ok := sortColumns[filter.SortField]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", filter.SortField)
 	}
+	name, cast, _ := strings.Cut(column, "::")
 
-	if serviceName != nil {
-		query += fmt.Sprintf(" AND service_name = $%d", argCount)
-		args = append(args, *serviceName)
+	direction, comparison := "ASC", ">"
+	if filter.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	// Выборка по ключу: продолжаем строго после последней записи предыдущей страницы
+	if filter.After != nil {
+		where += fmt.Sprintf(" AND (%s, id) %s ($%d::%s, $%d)", name, comparison, argCount, cast, argCount+1)
+		args = append(args, filter.After.Value, filter.After.ID)
+		argCount += 2
 	}
 
+	query := `SELECT ` + subscriptionColumns + ` 
+              FROM subscriptions WHERE ` + where +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d", name, direction, direction, argCount)
+	args = append(args, filter.Limit)
+
 	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
-		r.logger.WithError(err).Error("Ошибка при выполнении запроса списка подписок")
-		return nil, err
+		r.log(ctx).WithError(err).Error("Ошибка при выполнении запроса списка подписок")
+		return nil, translateError(err)
 	}
 	defer rows.Close()
 
-	var subscriptions []*model.Subscription
+	subscriptions := []*model.Subscription{}
 	for rows.Next() {
-		var sub model.Subscription
-		err := rows.Scan(
-			&sub.ServiceName, &sub.Price, &sub.UserID,
-			&sub.StartDate, &sub.EndDate,
-		)
+		sub, err := scanSubscription(rows)
 		if err != nil {
-			r.logger.WithError(err).Error("Ошибка при сканировании строки результата запроса")
+			r.log(ctx).WithError(err).Error("Ошибка при сканировании строки результата запроса")
 			return nil, err
 		}
-		subscriptions = append(subscriptions, &sub)
+		subscriptions = append(subscriptions, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log(ctx).WithError(err).Error("Ошибка при чтении результата запроса")
+		return nil, translateError(err)
 	}
 
-	r.logger.WithField("count", len(subscriptions)).Info("Получен список подписок")
+	r.log(ctx).WithField("count", len(subscriptions)).Info("Получен список подписок")
 	return subscriptions, nil
 }
 
-func (r *repo) Create(ctx context.Context, sub *model.Subscription) error {
+// Count возвращает количество подписок, подходящих под фильтры, без учёта постраничной выборки
+func (r *repo) Count(ctx context.Context, filter *model.ListFilter) (int, error) {
+	where, args := listConditions(filter)
 
-	exist, err := r.List(ctx, &sub.UserID, &sub.ServiceName)
+	var count int
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM subscriptions WHERE `+where, args...).Scan(&count)
 	if err != nil {
-		return err
+		r.log(ctx).WithError(err).Error("Ошибка при подсчёте количества подписок")
+		return 0, translateError(err)
 	}
 
-	if len(exist) > 0 {
-		return fmt.Errorf("exists")
+	return count, nil
+}
+
+// listConditions формирует условия WHERE по фильтрам списка подписок
+func listConditions(filter *model.ListFilter) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	if filter.IncludeDeleted {
+		conditions = []string{"1=1"}
 	}
+	args := []interface{}{}
 
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != nil {
+		add("user_id = $%d", *filter.UserID)
+	}
+	if filter.ServiceName != nil {
+		add("service_name = $%d", *filter.ServiceName)
+	}
+	if filter.ServicePrefix != nil {
+		// Спецсимволы LIKE в префиксе экранируются, чтобы искать их буквально
+		prefix := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(*filter.ServicePrefix)
+		add("service_name ILIKE ($%d || '%%')", prefix)
+	}
+	if filter.PriceMin != nil {
+		add("price >= $%d", *filter.PriceMin)
+	}
+	if filter.PriceMax != nil {
+		add("price <= $%d", *filter.PriceMax)
+	}
+	if filter.ActiveOn != nil {
+		add("daterange(start_date, end_date, '[]') @> $%d::date", *filter.ActiveOn)
+	}
+	if filter.Status != nil {
+		// Статус определяется относительно текущего месяца
+		switch *filter.Status {
+		case model.StatusActive:
+			conditions = append(conditions, "daterange(start_date, end_date, '[]') @> date_trunc('month', CURRENT_DATE)::date")
+		case model.StatusEnded:
+			conditions = append(conditions, "end_date < date_trunc('month', CURRENT_DATE)::date")
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// Create создаёт подписку и записывает создание в журнал аудита в одной транзакции
+func (r *repo) Create(ctx context.Context, sub *model.Subscription) error {
+	// Пересекающиеся периоды одной подписки отсекаются ограничением исключения в БД
 	query := `INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date) 
-              VALUES ($1, $2, $3, $4, $5) RETURNING user_id, service_name`
+              VALUES ($1, $2, $3, $4, $5) RETURNING id`
 
-	err = r.pool.QueryRow(ctx, query, sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate).
-		Scan(&sub.UserID, &sub.ServiceName)
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate).
+			Scan(&sub.ID)
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, model.AuditActionCreate, nil, sub)
+	})
 
 	if err != nil {
-		r.logger.WithError(err).Error("Ошибка при создании записи в таблице subscriptions")
-		return err
+		r.log(ctx).WithError(err).Error("Ошибка при создании записи в таблице subscriptions")
+		return translateError(err)
 	}
 
-	r.logger.WithFields(logrus.Fields{
+	r.log(ctx).WithFields(logrus.Fields{
+		"id":          sub.ID,
 		"serviceName": sub.ServiceName,
 		"userId":      sub.UserID,
 	}).Info("Запись о подписке успешно создана")
@@ -101,58 +200,180 @@ func (r *repo) Create(ctx context.Context, sub *model.Subscription) error {
 	return nil
 }
 
-func (r *repo) Get(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error) {
-	query := `SELECT service_name, price, user_id, start_date, end_date 
-              FROM subscriptions WHERE user_id = $1 AND service_name = $2`
+// Get возвращает последний по дате начала период подписки пользователя на сервис.
+// Удалённые периоды учитываются, только если includeDeleted
+func (r *repo) Get(ctx context.Context, userID *uuid.UUID, serviceName *string, includeDeleted bool) (*model.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` 
+              FROM subscriptions WHERE user_id = $1 AND service_name = $2 AND ($3 OR deleted_at IS NULL)
+              ORDER BY start_date DESC LIMIT 1`
 
-	var sub model.Subscription
-	err := r.pool.QueryRow(ctx, query, userID, serviceName).
-		Scan(&sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate)
+	return r.getOne(ctx, query, userID, serviceName, includeDeleted)
+}
+
+func (r *repo) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` 
+              FROM subscriptions WHERE id = $1 AND ($2 OR deleted_at IS NULL)`
+
+	return r.getOne(ctx, query, id, includeDeleted)
+}
+
+// GetDeleted возвращает последний удалённый период подписки пользователя на сервис
+func (r *repo) GetDeleted(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` 
+              FROM subscriptions WHERE user_id = $1 AND service_name = $2 AND deleted_at IS NOT NULL
+              ORDER BY deleted_at DESC LIMIT 1`
+
+	return r.getOne(ctx, query, userID, serviceName)
+}
 
+// FindOverlapping возвращает период той же подписки, пересекающийся с переданным, или nil, если такого нет
+func (r *repo) FindOverlapping(ctx context.Context, period *model.SubscriptionPeriod) (*model.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` 
+              FROM subscriptions
+              WHERE user_id = $1 AND service_name = $2 AND deleted_at IS NULL
+                AND daterange(start_date, end_date, '[]') && daterange($3::date, $4::date, '[]')
+                AND ($5::uuid IS NULL OR id <> $5)
+              ORDER BY start_date LIMIT 1`
+
+	sub, err := r.getOne(ctx, query, period.UserID, period.ServiceName, period.StartDate, period.EndDate, period.ExcludeID)
 	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, nil
+		}
+		r.log(ctx).WithError(err).Error("Ошибка при поиске пересекающихся периодов подписки")
 		return nil, err
 	}
 
-	return &sub, nil
+	return sub, nil
 }
 
-func (r *repo) Update(ctx context.Context, updSub *model.UpdateSubscription) (*model.Subscription, error) {
-	query := `UPDATE subscriptions SET 
-                price = COALESCE($1, price),
-                start_date = COALESCE($2, start_date),
-                end_date = COALESCE($3, end_date)
-              WHERE user_id = $4 AND service_name = $5 RETURNING *`
+// Update полностью заменяет данные подписки с идентификатором sub.ID, если её версия
+// по-прежнему равна sub.Version, и записывает изменение в журнал аудита в одной транзакции
+func (r *repo) Update(ctx context.Context, sub *model.Subscription) (*model.Subscription, error) {
+	selectQuery := `SELECT ` + subscriptionColumns + ` 
+              FROM subscriptions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	updateQuery := `UPDATE subscriptions SET 
+                service_name = $1,
+                price = $2,
+                user_id = $3,
+                start_date = $4,
+                end_date = $5,
+                version = version + 1
+              WHERE id = $6 RETURNING ` + subscriptionColumns
+
+	var updated *model.Subscription
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		before, err := scanSubscription(tx.QueryRow(ctx, selectQuery, sub.ID))
+		if err != nil {
+			return err
+		}
+		if before.Version != sub.Version {
+			return versionMismatch(before)
+		}
 
-	var sub model.Subscription
-	err := r.pool.QueryRow(ctx, query, updSub.Price, updSub.StartDate, updSub.EndDate, updSub.UserID, updSub.ServiceName).
-		Scan(&sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate)
+		updated, err = scanSubscription(tx.QueryRow(ctx, updateQuery,
+			sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate, sub.ID))
+		if err != nil {
+			return err
+		}
+
+		return insertAudit(ctx, tx, model.AuditActionUpdate, before, updated)
+	})
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	return &sub, nil
+	return updated, nil
+}
+
+// Delete помечает подписку удалённой, если её версия по-прежнему равна version,
+// и записывает удаление в журнал аудита в одной транзакции.
+// Удалённая подписка окончательно удаляется методом Purge по истечении срока хранения
+func (r *repo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	query := `UPDATE subscriptions SET deleted_at = now(), version = version + 1
+              WHERE id = $1 AND deleted_at IS NULL RETURNING ` + subscriptionColumns
+
+	_, err := r.changeDeleted(ctx, model.AuditActionDelete, query, id, version)
+	return err
+}
+
+// Restore снимает с подписки отметку об удалении, если её версия по-прежнему равна version,
+// и записывает восстановление в журнал аудита
+func (r *repo) Restore(ctx context.Context, id uuid.UUID, version int) (*model.Subscription, error) {
+	query := `UPDATE subscriptions SET deleted_at = NULL, version = version + 1
+              WHERE id = $1 AND deleted_at IS NOT NULL RETURNING ` + subscriptionColumns
+
+	return r.changeDeleted(ctx, model.AuditActionRestore, query, id, version)
+}
+
+// changeDeleted выполняет запрос query, изменяющий отметку об удалении подписки версии version,
+// и записывает изменение в журнал аудита в одной транзакции
+func (r *repo) changeDeleted(ctx context.Context, action, query string, id uuid.UUID, version int) (*model.Subscription, error) {
+	var after *model.Subscription
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		before, err := scanSubscription(tx.QueryRow(ctx, `SELECT `+subscriptionColumns+` 
+              FROM subscriptions WHERE id = $1 FOR UPDATE`, id))
+		if err != nil {
+			return err
+		}
+		if before.Version != version {
+			return versionMismatch(before)
+		}
+
+		after, err = scanSubscription(tx.QueryRow(ctx, query, id))
+		if err != nil {
+			return err
+		}
+
+		return insertAudit(ctx, tx, action, before, after)
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return after, nil
 }
 
-func (r *repo) Delete(ctx context.Context, userID *uuid.UUID, serviceName *string) error {
-	query := "DELETE FROM subscriptions WHERE user_id = $1 AND service_name = $2"
+// Purge окончательно удаляет подписки, удалённые раньше deletedBefore, и возвращает их количество
+func (r *repo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, "DELETE FROM subscriptions WHERE deleted_at < $1", deletedBefore)
+	if err != nil {
+		r.log(ctx).WithError(err).Error("Ошибка при удалении подписок с истёкшим сроком хранения")
+		return 0, translateError(err)
+	}
 
-	result, err := r.pool.Exec(ctx, query, userID, serviceName)
+	return result.RowsAffected(), nil
+}
+
+// getOne выполняет запрос, возвращающий одну подписку, и переводит ошибки в ошибки apperr
+func (r *repo) getOne(ctx context.Context, query string, args ...interface{}) (*model.Subscription, error) {
+	sub, err := scanSubscription(r.pool.QueryRow(ctx, query, args...))
 	if err != nil {
-		return err
+		return nil, translateError(err)
 	}
 
-	if result.RowsAffected() == 0 {
-		err := sql.ErrNoRows
-		return err
+	return sub, nil
+}
+
+func scanSubscription(row pgx.Row) (*model.Subscription, error) {
+	var sub model.Subscription
+	err := row.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.DeletedAt, &sub.Version)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &sub, nil
 }
 
 func (r *repo) GetTotal(ctx context.Context, req *model.TotalSubscription) (*model.TotalResponse, error) {
-	query := `SELECT COALESCE(SUM(price), 0), COUNT(*) 
-              FROM subscriptions 
+	// Выбираются подписки, пересекающиеся с периодом, стоимость считается по количеству
+	// оплаченных месяцев внутри периода (см. billedMonths)
+	query := `SELECT price, start_date, end_date
+              FROM subscriptions
               WHERE start_date <= $1 AND (end_date IS NULL OR end_date >= $2)`
+	if !req.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 	args := []interface{}{req.EndPeriod, req.StartPeriod}
 	argCount := 3
 
@@ -167,16 +388,201 @@ func (r *repo) GetTotal(ctx context.Context, req *model.TotalSubscription) (*mod
 		args = append(args, *req.ServiceName)
 	}
 
-	var total model.TotalResponse
-	err := r.pool.QueryRow(ctx, query, args...).Scan(&total.TotalCost, &total.Count)
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		r.log(ctx).WithError(err).Error("Ошибка при выполнении запроса стоимости подписок")
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var total model.TotalResponse
+	for rows.Next() {
+		var price int
+		var startDate time.Time
+		var endDate *time.Time
+		if err := rows.Scan(&price, &startDate, &endDate); err != nil {
+			r.log(ctx).WithError(err).Error("Ошибка при сканировании строки результата запроса")
+			return nil, err
+		}
+
+		months := billedMonths(startDate, endDate, req.StartPeriod, req.EndPeriod)
+		total.TotalCost += price * months
+		total.Months += months
+		total.Count++
 	}
 
-	r.logger.WithFields(logrus.Fields{
+	if err := rows.Err(); err != nil {
+		r.log(ctx).WithError(err).Error("Ошибка при чтении результата запроса")
+		return nil, translateError(err)
+	}
+
+	r.log(ctx).WithFields(logrus.Fields{
 		"totalCost": total.TotalCost,
 		"count":     total.Count,
+		"months":    total.Months,
 	}).Info("Сумма и количество подписок получены")
 
 	return &total, nil
 }
+
+// billedMonths возвращает количество оплаченных месяцев подписки внутри периода [from, to].
+// Даты подписки обрезаются границами периода, бессрочная подписка действует до конца периода,
+// оба граничных месяца оплачиваются целиком
+func billedMonths(start time.Time, end *time.Time, from, to time.Time) int {
+	if start.Before(from) {
+		start = from
+	}
+	if end == nil || end.After(to) {
+		end = &to
+	}
+	if end.Before(start) {
+		return 0
+	}
+
+	months := (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month())
+	if end.Day() < start.Day() {
+		months--
+	}
+
+	return months + 1
+}
+
+func (r *repo) GetTotalBreakdown(ctx context.Context, req *model.BreakdownSubscription) ([]*model.BreakdownItem, error) {
+	query, args := breakdownQuery(req)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		r.log(ctx).WithError(err).Error("Ошибка при выполнении запроса детализации расходов")
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	items := []*model.BreakdownItem{}
+	for rows.Next() {
+		var item model.BreakdownItem
+		var month time.Time
+
+		dest := make([]interface{}, 0, 5)
+		if req.GroupByMonth {
+			dest = append(dest, &month)
+		}
+		if req.GroupByServiceName {
+			dest = append(dest, &item.ServiceName)
+		}
+		if req.GroupByUserID {
+			dest = append(dest, &item.UserID)
+		}
+		dest = append(dest, &item.TotalCost, &item.Count)
+
+		if err := rows.Scan(dest...); err != nil {
+			r.log(ctx).WithError(err).Error("Ошибка при сканировании строки результата запроса")
+			return nil, err
+		}
+
+		if req.GroupByMonth {
+			monthStr := month.Format("01-2006")
+			item.Month = &monthStr
+		}
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log(ctx).WithError(err).Error("Ошибка при чтении результата запроса")
+		return nil, err
+	}
+
+	r.log(ctx).WithField("count", len(items)).Info("Получена детализация расходов по подпискам")
+	return items, nil
+}
+
+// breakdownQuery строит запрос детализации расходов. Каждая подписка разворачивается в строки
+// по месяцам периода, в которых она действовала, поэтому сумма цен по группе равна стоимости
+// подписок за соответствующие месяцы. Подписки присоединяются через LEFT JOIN с фильтрами в ON,
+// чтобы месяцы без расходов попадали в результат с нулевой суммой
+func breakdownQuery(req *model.BreakdownSubscription) (string, []interface{}) {
+	var groups []string
+	if req.GroupByMonth {
+		groups = append(groups, "m.month")
+	}
+	if req.GroupByServiceName {
+		groups = append(groups, "s.service_name")
+	}
+	if req.GroupByUserID {
+		groups = append(groups, "s.user_id")
+	}
+
+	columns := append(append([]string{}, groups...),
+		"COALESCE(SUM(s.price), 0)", "COUNT(DISTINCT s.id)")
+
+	query := fmt.Sprintf(`SELECT %s
+              FROM (SELECT generate_series($2::timestamp, $1::timestamp, interval '1 month')::date AS month) AS m
+              LEFT JOIN subscriptions s ON s.start_date <= m.month AND (s.end_date IS NULL OR s.end_date >= m.month)`,
+		strings.Join(columns, ", "))
+	if !req.IncludeDeleted {
+		query += " AND s.deleted_at IS NULL"
+	}
+	args := []interface{}{req.EndPeriod, req.StartPeriod}
+	argCount := 3
+
+	if req.UserID != nil {
+		query += fmt.Sprintf(" AND s.user_id = $%d", argCount)
+		args = append(args, *req.UserID)
+		argCount++
+	}
+
+	if req.ServiceName != nil {
+		query += fmt.Sprintf(" AND s.service_name = $%d", argCount)
+		args = append(args, *req.ServiceName)
+	}
+
+	// Без группировки по месяцам пустые месяцы дали бы лишнюю группу с NULL вместо сервиса или пользователя
+	if !req.GroupByMonth {
+		query += " WHERE s.id IS NOT NULL"
+	}
+
+	if len(groups) > 0 {
+		query += " GROUP BY " + strings.Join(groups, ", ") + " ORDER BY " + strings.Join(groups, ", ")
+	}
+
+	return query, args
+}
+
+// ServiceStats возвращает количество и суммарную месячную стоимость подписок,
+// действующих в текущем месяце, по каждому сервису
+func (r *repo) ServiceStats(ctx context.Context) ([]*model.ServiceStats, error) {
+	query := `SELECT service_name, COUNT(*), SUM(price)
+              FROM subscriptions
+              WHERE deleted_at IS NULL
+                AND daterange(start_date, end_date, '[]') @> date_trunc('month', CURRENT_DATE)::date
+              GROUP BY service_name`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		r.log(ctx).WithError(err).Error("Ошибка при выполнении запроса статистики подписок")
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	stats := []*model.ServiceStats{}
+	for rows.Next() {
+		var stat model.ServiceStats
+		if err := rows.Scan(&stat.ServiceName, &stat.ActiveCount, &stat.MonthlySpend); err != nil {
+			r.log(ctx).WithError(err).Error("Ошибка при сканировании строки результата запроса")
+			return nil, err
+		}
+		stats = append(stats, &stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log(ctx).WithError(err).Error("Ошибка при чтении результата запроса")
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// versionMismatch возвращает ошибку изменения подписки, версия которой изменилась после чтения
+func versionMismatch(current *model.Subscription) error {
+	version := strconv.Itoa(current.Version)
+	return apperr.PreconditionFailed(apperr.CodeVersionMismatch, "Подписка была изменена, текущая версия "+version).WithParam(version)
+}
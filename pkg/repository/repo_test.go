@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"strings"
+	"subscription_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestBilledMonths(t *testing.T) {
+	from, to := month(2025, time.January), month(2025, time.December)
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   *time.Time
+		want  int
+	}{
+		{"внутри периода", month(2025, time.March), ptr(month(2025, time.May)), 3},
+		{"один месяц", month(2025, time.July), ptr(month(2025, time.July)), 1},
+		{"начало в середине периода", month(2025, time.September), ptr(month(2026, time.June)), 4},
+		{"начало до периода", month(2024, time.October), ptr(month(2025, time.February)), 2},
+		{"бессрочная с начала в середине периода", month(2025, time.June), nil, 7},
+		{"бессрочная с начала до периода", month(2023, time.May), nil, 12},
+		{"охватывает период", month(2024, time.January), ptr(month(2026, time.January)), 12},
+		{"закончилась до периода", month(2024, time.January), ptr(month(2024, time.December)), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := billedMonths(tt.start, tt.end, from, to); got != tt.want {
+				t.Errorf("billedMonths() = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBilledMonthsAcrossYears(t *testing.T) {
+	// Период захватывает два года, бессрочная подписка началась в его середине
+	from, to := month(2024, time.November), month(2025, time.February)
+
+	if got := billedMonths(month(2024, time.December), nil, from, to); got != 3 {
+		t.Errorf("billedMonths() = %d, ожидалось 3", got)
+	}
+}
+
+func TestBreakdownQueryKeepsEmptyMonths(t *testing.T) {
+	userID := uuid.New()
+	serviceName := "Yandex Plus"
+	req := &model.BreakdownSubscription{
+		TotalSubscription: model.TotalSubscription{
+			UserID:      &userID,
+			ServiceName: &serviceName,
+			StartPeriod: month(2025, time.January),
+			EndPeriod:   month(2025, time.March),
+		},
+		GroupByMonth: true,
+	}
+
+	query, args := breakdownQuery(req)
+
+	// Месяц без подписок остаётся в результате, только если все фильтры стоят в условии соединения
+	join := strings.Index(query, "LEFT JOIN subscriptions s ON")
+	if join < 0 {
+		t.Fatalf("подписки должны присоединяться через LEFT JOIN: %s", query)
+	}
+	if strings.Contains(query, "WHERE") {
+		t.Errorf("фильтры в WHERE отбросят пустые месяцы: %s", query)
+	}
+	for _, filter := range []string{"s.deleted_at IS NULL", "s.user_id = $3", "s.service_name = $4"} {
+		if i := strings.Index(query, filter); i < join {
+			t.Errorf("фильтр %q должен стоять в условии соединения: %s", filter, query)
+		}
+	}
+	if !strings.Contains(query, "COALESCE(SUM(s.price), 0)") {
+		t.Errorf("сумма пустого месяца должна быть нулевой: %s", query)
+	}
+	if !strings.Contains(query, "COUNT(DISTINCT s.id)") {
+		t.Errorf("пустой месяц должен давать нулевое количество подписок: %s", query)
+	}
+	if len(args) != 4 || args[2] != userID || args[3] != serviceName {
+		t.Errorf("аргументы запроса %v", args)
+	}
+}
+
+func TestBreakdownQueryWithoutMonths(t *testing.T) {
+	req := &model.BreakdownSubscription{
+		TotalSubscription: model.TotalSubscription{
+			StartPeriod: month(2025, time.January),
+			EndPeriod:   month(2025, time.March),
+		},
+		GroupByServiceName: true,
+	}
+
+	query, args := breakdownQuery(req)
+
+	// Без группировки по месяцам пустые месяцы не должны порождать группу с NULL
+	if !strings.Contains(query, "WHERE s.id IS NOT NULL") {
+		t.Errorf("пустые месяцы должны отбрасываться: %s", query)
+	}
+	if !strings.HasSuffix(query, "GROUP BY s.service_name ORDER BY s.service_name") {
+		t.Errorf("неверная группировка: %s", query)
+	}
+	if len(args) != 2 {
+		t.Errorf("аргументы запроса %v", args)
+	}
+}
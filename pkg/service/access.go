@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+
+	"github.com/google/uuid"
+)
+
+// getOwned возвращает последний период подписки, если политика разрешает пользователю запроса действие action с ней.
+// Удалённые периоды учитываются, только если includeDeleted
+func (s *subService) getOwned(ctx context.Context, action string, userID *uuid.UUID, serviceName *string, includeDeleted bool) (*model.Subscription, error) {
+	if err := s.policy.Authorize(ctx, action, *userID); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, userID, serviceName, includeDeleted)
+}
+
+// getOwnedByID возвращает подписку по ID, если политика разрешает пользователю запроса действие action с ней.
+// Удалённая подписка возвращается, только если includeDeleted.
+// Подписка, которую пользователю запроса нельзя читать, неотличима от несуществующей, чтобы по ответу нельзя было перебирать ID
+func (s *subService) getOwnedByID(ctx context.Context, action string, id uuid.UUID, includeDeleted bool) (*model.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(ctx, action, sub.UserID); err != nil {
+		if !s.policy.canRead(ctx, sub.UserID) {
+			return nil, apperr.NotFound(apperr.CodeSubscriptionNotFound, "Подписка не найдена")
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// scopeDeleted дополнительно ограничивает фильтр по пользователю, если в выборку включаются удалённые подписки
+func (s *subService) scopeDeleted(ctx context.Context, includeDeleted bool, userID *uuid.UUID) (*uuid.UUID, error) {
+	if !includeDeleted {
+		return userID, nil
+	}
+	return s.policy.Scope(ctx, ActionReadDeleted, userID)
+}
+
+// checkIfMatch проверяет условие If-Match перед изменением подписки.
+// Совпадение версии при записи дополнительно проверяет репозиторий
+func (s *subService) checkIfMatch(sub *model.Subscription, ifMatch []string) error {
+	if ifMatch == nil {
+		if s.requireIfMatch {
+			return apperr.PreconditionRequired(apperr.CodeIfMatchRequired, "Требуется заголовок If-Match с ETag подписки")
+		}
+		return nil
+	}
+
+	if !model.MatchETag(ifMatch, sub.ETag(), false) {
+		version := strconv.Itoa(sub.Version)
+		return apperr.PreconditionFailed(apperr.CodeVersionMismatch, "Подписка была изменена, текущая версия "+version).WithParam(version)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGetOwnedByIDHidesForeignSubscriptions(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	own := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: owner, StartDate: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Version: 1}
+	foreign := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: other, StartDate: own.StartDate, Version: 1}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		id       uuid.UUID
+		wantErr  error
+		wantCode string
+	}{
+		{"своя подписка", userContext(owner), own.ID, nil, ""},
+		{"чужая подписка", userContext(owner), foreign.ID, apperr.ErrNotFound, apperr.CodeSubscriptionNotFound},
+		{"несуществующая подписка", userContext(owner), uuid.New(), apperr.ErrNotFound, apperr.CodeSubscriptionNotFound},
+		{"чужая подписка с доступом на чтение", userContext(owner, "support"), foreign.ID, nil, ""},
+		{"своя подписка с дополнительной ролью", userContext(owner, "support"), own.ID, nil, ""},
+		{"без аутентификации", context.Background(), foreign.ID, nil, ""},
+	}
+
+	svc := NewSubService(newFakeRepo(own, foreign), DefaultPolicy(newTestLogger()), false, newTestLogger())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.GetSubscriptionByID(tt.ctx, tt.id, false)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("GetSubscriptionByID() error = %v", err)
+			}
+			if tt.wantErr != nil && (!errors.Is(err, tt.wantErr) || errorCode(err) != tt.wantCode) {
+				t.Fatalf("GetSubscriptionByID() error = %v, ожидалась %v с кодом %q", err, tt.wantErr, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDeleteForeignSubscriptionByID(t *testing.T) {
+	owner := uuid.New()
+	foreign := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: uuid.New(), StartDate: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Version: 1}
+	svc := NewSubService(newFakeRepo(foreign), DefaultPolicy(newTestLogger()), false, newTestLogger())
+
+	// Поддержке разрешено читать чужую подписку, но не удалять её: существование уже известно, поэтому 403
+	err := svc.DeleteSubscriptionByID(userContext(owner, "support"), foreign.ID, nil)
+	if !errors.Is(err, apperr.ErrForbidden) || errorCode(err) != apperr.CodeAccessDenied {
+		t.Fatalf("DeleteSubscriptionByID() error = %v, ожидался отказ в доступе", err)
+	}
+
+	// Владельцу чужая подписка не видна вовсе
+	err = svc.DeleteSubscriptionByID(userContext(owner), foreign.ID, nil)
+	if !errors.Is(err, apperr.ErrNotFound) {
+		t.Fatalf("DeleteSubscriptionByID() error = %v, ожидалось «не найдено»", err)
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	sub := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: uuid.New(),
+		StartDate: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Version: 4}
+	stale := &model.Subscription{ID: sub.ID, Version: 3}
+
+	tests := []struct {
+		name           string
+		requireIfMatch bool
+		ifMatch        []string
+		wantCode       string
+	}{
+		{"без заголовка", false, nil, ""},
+		{"без обязательного заголовка", true, nil, apperr.CodeIfMatchRequired},
+		{"текущая версия", true, []string{sub.ETag()}, ""},
+		{"любая версия", true, []string{"*"}, ""},
+		{"устаревшая версия", false, []string{stale.ETag()}, apperr.CodeVersionMismatch},
+		{"слабый тег", false, []string{"W/" + sub.ETag()}, apperr.CodeVersionMismatch},
+		{"пустой заголовок из одних запятых", false, []string{}, apperr.CodeVersionMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &subService{requireIfMatch: tt.requireIfMatch}
+			err := svc.checkIfMatch(sub, tt.ifMatch)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("checkIfMatch() error = %v, ожидался код %q", err, tt.wantCode)
+			}
+			if tt.wantCode == apperr.CodeVersionMismatch {
+				if appErr, _ := apperr.As(err); appErr.Param != "4" {
+					t.Errorf("в ошибке версия %q, ожидалась текущая версия 4", appErr.Param)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateWithStaleETag(t *testing.T) {
+	owner := uuid.New()
+	sub := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: owner,
+		StartDate: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Version: 1}
+	etag := sub.ETag()
+	svc := NewSubService(newFakeRepo(sub), DefaultPolicy(newTestLogger()), true, newTestLogger())
+	patch := &model.PatchSubscriptionRequest{Price: model.Optional[int]{Set: true, Value: 500}}
+
+	updated, err := svc.PatchSubscriptionByID(userContext(owner), sub.ID, patch, []string{etag})
+	if err != nil {
+		t.Fatalf("PatchSubscriptionByID() error = %v", err)
+	}
+	if updated.ETag() == etag {
+		t.Fatalf("ETag не изменился после изменения подписки: %s", etag)
+	}
+
+	// Повтор с прежним ETag означает, что клиент не видел последнее изменение
+	_, err = svc.PatchSubscriptionByID(userContext(owner), sub.ID, patch, []string{etag})
+	if !errors.Is(err, apperr.ErrPreconditionFailed) || errorCode(err) != apperr.CodeVersionMismatch {
+		t.Fatalf("PatchSubscriptionByID() error = %v, ожидался код %q", err, apperr.CodeVersionMismatch)
+	}
+
+	if err := svc.DeleteSubscriptionByID(userContext(owner), sub.ID, nil); errorCode(err) != apperr.CodeIfMatchRequired {
+		t.Fatalf("DeleteSubscriptionByID() без If-Match: error = %v, ожидался код %q", err, apperr.CodeIfMatchRequired)
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Префикс ключей API, упрощает их поиск в логах и репозиториях кода
+	apiKeyPrefix = "sk_"
+	// Количество символов ключа, сохраняемых открыто для опознания ключа
+	apiKeyVisibleLength = 8
+	// Время использования ключа обновляется не чаще этого интервала
+	apiKeyTouchInterval = time.Minute
+)
+
+type APIKeyService interface {
+	// Create создаёт ключ и возвращает его значение, которое больше нигде не хранится
+	Create(ctx context.Context, name string, scopes []string) (string, *model.APIKey, error)
+	List(ctx context.Context) ([]*model.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// Authenticate проверяет ключ и возвращает внутренний сервис, выполняющий запрос
+	Authenticate(ctx context.Context, rawKey string) (*auth.Principal, error)
+}
+
+type apiKeyService struct {
+	repo   repository.APIKeyRepository
+	logger *logrus.Logger
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, logger *logrus.Logger) APIKeyService {
+	return &apiKeyService{repo: repo, logger: logger}
+}
+
+func (s *apiKeyService) Create(ctx context.Context, name string, scopes []string) (string, *model.APIKey, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("key name is required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(auth.Scopes, scope) {
+			return "", nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	rawKey := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &model.APIKey{
+		Name:   name,
+		Prefix: rawKey[:len(apiKeyPrefix)+apiKeyVisibleLength],
+		Scopes: scopes,
+	}
+	if err := s.repo.Create(ctx, key, hashAPIKey(rawKey)); err != nil {
+		return "", nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).WithFields(logrus.Fields{
+		"apiKeyId": key.ID,
+		"name":     key.Name,
+		"scopes":   key.Scopes,
+	}).Info("Создан ключ API")
+
+	return rawKey, key, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context) ([]*model.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).WithField("apiKeyId", id).Info("Ключ API отозван")
+	return nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*auth.Principal, error) {
+	key, err := s.repo.GetByHash(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		if _, ok := apperr.As(err); !ok {
+			return nil, err
+		}
+		return nil, apperr.Wrap(apperr.ErrUnauthorized, apperr.CodeInvalidAPIKey, "Ключ API недействителен или отозван", err)
+	}
+
+	if key.RevokedAt != nil {
+		return nil, apperr.Unauthorized(apperr.CodeInvalidAPIKey, "Ключ API недействителен или отозван")
+	}
+
+	// Время использования обновляется с точностью до интервала, чтобы не писать в БД на каждый запрос
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			logging.FromContext(ctx, s.logger).WithError(err).Warn("Не удалось обновить время использования ключа API")
+		}
+	}
+
+	return &auth.Principal{APIKeyID: &key.ID, Scopes: key.Scopes}, nil
+}
+
+// hashAPIKey возвращает SHA-256 ключа. Ключи случайные и длинные, поэтому соль не требуется
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
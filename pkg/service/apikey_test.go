@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeAPIKeyRepo хранит ключи API в памяти по SHA-256 значения ключа
+type fakeAPIKeyRepo struct {
+	keys    map[string]*model.APIKey
+	touched []uuid.UUID
+}
+
+func newFakeAPIKeyRepo() *fakeAPIKeyRepo {
+	return &fakeAPIKeyRepo{keys: map[string]*model.APIKey{}}
+}
+
+func (r *fakeAPIKeyRepo) Create(_ context.Context, key *model.APIKey, keyHash string) error {
+	key.ID, key.CreatedAt = uuid.New(), time.Now()
+	r.keys[keyHash] = key
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) GetByHash(_ context.Context, keyHash string) (*model.APIKey, error) {
+	key, ok := r.keys[keyHash]
+	if !ok {
+		return nil, apperr.NotFound(apperr.CodeAPIKeyNotFound, "Ключ API не найден")
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (r *fakeAPIKeyRepo) List(context.Context) ([]*model.APIKey, error) {
+	keys := []*model.APIKey{}
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepo) Revoke(_ context.Context, id uuid.UUID) error {
+	for _, key := range r.keys {
+		if key.ID == id {
+			now := time.Now()
+			key.RevokedAt = &now
+			return nil
+		}
+	}
+	return apperr.NotFound(apperr.CodeAPIKeyNotFound, "Ключ API не найден")
+}
+
+func (r *fakeAPIKeyRepo) TouchLastUsed(_ context.Context, id uuid.UUID, usedAt time.Time) error {
+	r.touched = append(r.touched, id)
+	for _, key := range r.keys {
+		if key.ID == id {
+			key.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func TestHashAPIKey(t *testing.T) {
+	hash := hashAPIKey("sk_first")
+
+	if len(hash) != 64 || strings.Trim(hash, "0123456789abcdef") != "" {
+		t.Errorf("hashAPIKey() = %q, ожидался SHA-256 в шестнадцатеричном виде", hash)
+	}
+	if hashAPIKey("sk_first") != hash {
+		t.Error("хеш одного ключа различается")
+	}
+	if hashAPIKey("sk_second") == hash {
+		t.Error("хеши разных ключей совпадают")
+	}
+}
+
+func TestAPIKeyCreate(t *testing.T) {
+	repo := newFakeAPIKeyRepo()
+	svc := NewAPIKeyService(repo, newTestLogger())
+
+	rawKey, key, err := svc.Create(context.Background(), "billing", []string{auth.ScopeSubscriptionsRead})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) || key.Prefix != rawKey[:len(apiKeyPrefix)+apiKeyVisibleLength] {
+		t.Errorf("ключ %q с открытой частью %q", rawKey, key.Prefix)
+	}
+	// Хранится только хеш ключа, значение ключа нигде не сохраняется
+	if stored, ok := repo.keys[hashAPIKey(rawKey)]; !ok || stored.ID != key.ID {
+		t.Errorf("ключ не найден по хешу значения")
+	}
+
+	for _, tt := range []struct {
+		name   string
+		scopes []string
+	}{
+		{"", []string{auth.ScopeSubscriptionsRead}},
+		{"billing", nil},
+		{"billing", []string{"subscriptions:delete"}},
+	} {
+		if _, _, err := svc.Create(context.Background(), tt.name, tt.scopes); err == nil {
+			t.Errorf("Create(%q, %v) без ошибки", tt.name, tt.scopes)
+		}
+	}
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	repo := newFakeAPIKeyRepo()
+	svc := NewAPIKeyService(repo, newTestLogger())
+	ctx := context.Background()
+
+	rawKey, key, err := svc.Create(ctx, "billing", []string{auth.ScopeSubscriptionsRead, auth.ScopeReportsRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := svc.Authenticate(ctx, rawKey)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if !principal.IsService() || *principal.APIKeyID != key.ID || !principal.HasScope(auth.ScopeReportsRead) || principal.HasScope(auth.ScopeSubscriptionsWrite) {
+		t.Errorf("Authenticate() = %+v", principal)
+	}
+
+	// Время использования обновляется не чаще раза в apiKeyTouchInterval
+	if _, err := svc.Authenticate(ctx, rawKey); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.touched) != 1 {
+		t.Errorf("время использования обновлено %d раз, ожидалось 1", len(repo.touched))
+	}
+
+	if _, err := svc.Authenticate(ctx, rawKey+"x"); !errors.Is(err, apperr.ErrUnauthorized) || errorCode(err) != apperr.CodeInvalidAPIKey {
+		t.Errorf("Authenticate() с неверным ключом error = %v, ожидался код %s", err, apperr.CodeInvalidAPIKey)
+	}
+
+	if err := svc.Revoke(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate(ctx, rawKey); !errors.Is(err, apperr.ErrUnauthorized) || errorCode(err) != apperr.CodeInvalidAPIKey {
+		t.Errorf("Authenticate() с отозванным ключом error = %v, ожидался код %s", err, apperr.CodeInvalidAPIKey)
+	}
+}
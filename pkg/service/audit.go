@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditService interface {
+	// History возвращает журнал изменений подписки пользователя на сервис
+	History(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.AuditRequest) (*model.AuditList, error)
+	// List возвращает журнал изменений подписок с фильтрами по исполнителю, пользователю, сервису и времени
+	List(ctx context.Context, req *model.AuditRequest) (*model.AuditList, error)
+}
+
+type auditService struct {
+	repo   repository.AuditRepository
+	policy *Policy
+	logger *logrus.Logger
+}
+
+func NewAuditService(repo repository.AuditRepository, policy *Policy, logger *logrus.Logger) AuditService {
+	return &auditService{repo: repo, policy: policy, logger: logger}
+}
+
+func (s *auditService) History(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.AuditRequest) (*model.AuditList, error) {
+	if err := s.policy.Authorize(ctx, ActionRead, *userID); err != nil {
+		return nil, err
+	}
+
+	filter, err := parseAuditRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	filter.UserID = userID
+	filter.ServiceName = serviceName
+
+	return s.list(ctx, filter)
+}
+
+func (s *auditService) List(ctx context.Context, req *model.AuditRequest) (*model.AuditList, error) {
+	filter, err := parseAuditRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.UserID, err = s.policy.Scope(ctx, ActionAudit, filter.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.list(ctx, filter)
+}
+
+func (s *auditService) list(ctx context.Context, filter *model.AuditFilter) (*model.AuditList, error) {
+	entries, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuditList{Items: entries}, nil
+}
+
+// parseAuditRequest разбирает и проверяет параметры запроса журнала аудита
+func parseAuditRequest(req *model.AuditRequest) (*model.AuditFilter, error) {
+	filter := &model.AuditFilter{
+		ServiceName: req.ServiceName,
+		Action:      req.Action,
+		Limit:       req.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+
+	validationErr := apperr.Validation(apperr.CodeInvalidQueryParams, "Неверные параметры запроса")
+
+	parseUUID := func(field string, value *string) *uuid.UUID {
+		if value == nil || *value == "" {
+			return nil
+		}
+		parsed, err := uuid.Parse(*value)
+		if err != nil {
+			validationErr.Add(field, apperr.FieldInvalidUUID, "Ожидается UUID")
+			return nil
+		}
+		return &parsed
+	}
+	parseTime := func(field string, value *string) *time.Time {
+		if value == nil || *value == "" {
+			return nil
+		}
+		parsed, err := time.Parse(time.RFC3339, *value)
+		if err != nil {
+			validationErr.Add(field, apperr.FieldInvalidTimestamp, "Неверный формат времени, ожидается RFC 3339")
+			return nil
+		}
+		return &parsed
+	}
+
+	filter.ActorID = parseUUID("actor_id", req.ActorID)
+	filter.UserID = parseUUID("user_id", req.UserID)
+	filter.From = parseTime("from", req.From)
+	filter.To = parseTime("to", req.To)
+
+	if validationErr.HasErrors() {
+		return nil, validationErr
+	}
+
+	if filter.From != nil {
+		if err := validatePeriod("to", *filter.From, filter.To); err != nil {
+			return nil, err
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeAuditRepo запоминает фильтр последнего запроса журнала
+type fakeAuditRepo struct {
+	repository.AuditRepository
+
+	filter *model.AuditFilter
+}
+
+func (r *fakeAuditRepo) List(_ context.Context, filter *model.AuditFilter) ([]*model.AuditEntry, error) {
+	r.filter = filter
+	return nil, nil
+}
+
+func TestParseAuditRequest(t *testing.T) {
+	actorID := uuid.New()
+
+	tests := []struct {
+		name       string
+		req        model.AuditRequest
+		want       model.AuditFilter
+		wantCode   string
+		wantFields []string
+	}{
+		{
+			name: "значения по умолчанию",
+			want: model.AuditFilter{Limit: defaultListLimit},
+		},
+		{
+			name: "все фильтры",
+			req: model.AuditRequest{ActorID: ptr(actorID.String()), Action: ptr(model.AuditActionDelete),
+				From: ptr("2025-07-01T00:00:00Z"), To: ptr("2025-07-31T23:59:59+03:00"), Limit: 10},
+			want: model.AuditFilter{ActorID: &actorID, Action: ptr(model.AuditActionDelete),
+				From: ptr(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)), To: ptr(time.Date(2025, 7, 31, 20, 59, 59, 0, time.UTC)), Limit: 10},
+		},
+		{
+			name: "пустые параметры не фильтруют",
+			req:  model.AuditRequest{ActorID: ptr(""), From: ptr("")},
+			want: model.AuditFilter{Limit: defaultListLimit},
+		},
+		{
+			name:       "неверные UUID и время",
+			req:        model.AuditRequest{ActorID: ptr("1"), UserID: ptr("user"), From: ptr("07-2025")},
+			wantCode:   apperr.CodeInvalidQueryParams,
+			wantFields: []string{"actor_id", "user_id", "from"},
+		},
+		{
+			name:       "конец периода раньше начала",
+			req:        model.AuditRequest{From: ptr("2025-07-02T00:00:00Z"), To: ptr("2025-07-01T00:00:00Z")},
+			wantCode:   apperr.CodeInvalidPeriod,
+			wantFields: []string{"to"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseAuditRequest(&tt.req)
+
+			if tt.wantCode != "" {
+				appErr, ok := apperr.As(err)
+				if !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("parseAuditRequest() error = %v, ожидался код %q", err, tt.wantCode)
+				}
+				if fields := fieldNames(appErr); !slices.Equal(fields, tt.wantFields) {
+					t.Errorf("ошибки полей %v, ожидались %v", fields, tt.wantFields)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseAuditRequest() error = %v", err)
+			}
+			if !equalPtr(filter.ActorID, tt.want.ActorID) || !equalPtr(filter.UserID, tt.want.UserID) ||
+				!equalPtr(filter.Action, tt.want.Action) || filter.Limit != tt.want.Limit {
+				t.Errorf("фильтр %+v, ожидался %+v", filter, tt.want)
+			}
+			if !equalTime(filter.From, tt.want.From) || !equalTime(filter.To, tt.want.To) {
+				t.Errorf("период %v - %v, ожидался %v - %v", filter.From, filter.To, tt.want.From, tt.want.To)
+			}
+		})
+	}
+}
+
+func TestAuditListScope(t *testing.T) {
+	userID, other := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		req      model.AuditRequest
+		wantUser *uuid.UUID
+		wantCode string
+	}{
+		{"администратор видит всех пользователей", userContext(userID, auth.RoleAdmin), model.AuditRequest{}, nil, ""},
+		{"администратор с фильтром по пользователю", userContext(userID, auth.RoleAdmin), model.AuditRequest{UserID: ptr(other.String())}, &other, ""},
+		{"владельцу журнал не разрешён", userContext(userID), model.AuditRequest{}, nil, apperr.CodeActionNotPermitted},
+		{"без аутентификации", context.Background(), model.AuditRequest{}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeAuditRepo{}
+			svc := NewAuditService(repo, DefaultPolicy(newTestLogger()), newTestLogger())
+
+			_, err := svc.List(tt.ctx, &tt.req)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("List() error = %v, ожидался код %q", err, tt.wantCode)
+			}
+			if err == nil && !equalPtr(repo.filter.UserID, tt.wantUser) {
+				t.Errorf("фильтр по пользователю %v, ожидался %v", repo.filter.UserID, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestAuditHistory(t *testing.T) {
+	owner := uuid.New()
+	repo := &fakeAuditRepo{}
+	svc := NewAuditService(repo, DefaultPolicy(newTestLogger()), newTestLogger())
+
+	// Историю своей подписки владелец читает, даже если в параметрах передан другой пользователь
+	other := uuid.New().String()
+	if _, err := svc.History(userContext(owner), &owner, ptr("Yandex Plus"), &model.AuditRequest{UserID: &other}); err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if !equalPtr(repo.filter.UserID, &owner) || !equalPtr(repo.filter.ServiceName, ptr("Yandex Plus")) {
+		t.Errorf("фильтр %+v, ожидалась подписка владельца", repo.filter)
+	}
+
+	// История чужой подписки недоступна
+	foreign := uuid.New()
+	if _, err := svc.History(userContext(owner), &foreign, ptr("Yandex Plus"), &model.AuditRequest{}); errorCode(err) != apperr.CodeAccessDenied {
+		t.Fatalf("History() error = %v, ожидался отказ в доступе", err)
+	}
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func equalTime(a, b *time.Time) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && a.Equal(*b))
+}
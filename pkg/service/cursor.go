@@ -0,0 +1,55 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"subscription_service/pkg/model"
+	"time"
+)
+
+// encodeCursor формирует непрозрачный курсор, указывающий на подписку sub.
+// В курсор записывается исходный параметр sort, чтобы курсор нельзя было применить к другой сортировке
+func encodeCursor(sort, sortField string, sub *model.Subscription) (string, error) {
+	cursor := model.ListCursor{Sort: sort, ID: sub.ID}
+	switch sortField {
+	case model.SortByPrice:
+		cursor.Value = strconv.Itoa(sub.Price)
+	case model.SortByServiceName:
+		cursor.Value = sub.ServiceName
+	default:
+		cursor.Value = sub.StartDate.Format(time.DateOnly)
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor разбирает курсор и проверяет, что значение в нём соответствует типу поля сортировки
+func decodeCursor(encoded, sortField string) (*model.ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor model.ListCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	switch sortField {
+	case model.SortByPrice:
+		_, err = strconv.Atoi(cursor.Value)
+	case model.SortByStartDate:
+		_, err = time.Parse(time.DateOnly, cursor.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"encoding/base64"
+	"subscription_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	sub := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, StartDate: month(2025, time.July)}
+
+	tests := []struct {
+		sort      string
+		sortField string
+		wantValue string
+	}{
+		{"", model.SortByStartDate, "2025-07-01"},
+		{"-start_date", model.SortByStartDate, "2025-07-01"},
+		{"price", model.SortByPrice, "400"},
+		{"-service_name", model.SortByServiceName, "Yandex Plus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			encoded, err := encodeCursor(tt.sort, tt.sortField, sub)
+			if err != nil {
+				t.Fatalf("encodeCursor() error = %v", err)
+			}
+			cursor, err := decodeCursor(encoded, tt.sortField)
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			want := model.ListCursor{Sort: tt.sort, Value: tt.wantValue, ID: sub.ID}
+			if *cursor != want {
+				t.Errorf("decodeCursor() = %+v, ожидалось %+v", *cursor, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name      string
+		encoded   string
+		sortField string
+	}{
+		{"не base64", "***", model.SortByStartDate},
+		{"не JSON", encode("cursor"), model.SortByStartDate},
+		{"неверный ID", encode(`{"s":"","v":"2025-07-01","id":"1"}`), model.SortByStartDate},
+		{"дата другого формата", encode(`{"s":"","v":"07-2025","id":"` + uuid.NewString() + `"}`), model.SortByStartDate},
+		{"цена не число", encode(`{"s":"price","v":"Yandex Plus","id":"` + uuid.NewString() + `"}`), model.SortByPrice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := decodeCursor(tt.encoded, tt.sortField); err == nil {
+				t.Errorf("decodeCursor() = %+v, ожидалась ошибка", cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Максимальное время выполнения одной проверки готовности
+const healthCheckTimeout = 2 * time.Second
+
+type HealthService interface {
+	// Check выполняет проверки зависимостей сервиса
+	Check(ctx context.Context) *model.HealthReport
+}
+
+type healthService struct {
+	repo repository.HealthRepository
+	// Версия миграций, которую ожидает код сервиса
+	migrationVersion uint
+	logger           *logrus.Logger
+}
+
+func NewHealthService(repo repository.HealthRepository, migrationVersion uint, logger *logrus.Logger) HealthService {
+	return &healthService{repo: repo, migrationVersion: migrationVersion, logger: logger}
+}
+
+func (s *healthService) Check(ctx context.Context) *model.HealthReport {
+	report := &model.HealthReport{Status: model.HealthStatusUp}
+
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{"database", s.repo.Ping},
+		{"migrations", s.checkMigrations},
+	}
+
+	for _, c := range checks {
+		result := runCheck(ctx, c.name, c.check)
+		if result.Status != model.HealthStatusUp {
+			report.Status = model.HealthStatusDown
+			logging.FromContext(ctx, s.logger).WithFields(logrus.Fields{
+				"check": result.Name,
+				"error": result.Error,
+			}).Warn("Проверка готовности не пройдена")
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report
+}
+
+// checkMigrations проверяет, что БД находится на ожидаемой версии миграций
+func (s *healthService) checkMigrations(ctx context.Context) error {
+	version, dirty, err := s.repo.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if version != s.migrationVersion {
+		return fmt.Errorf("migration version %d, expected %d", version, s.migrationVersion)
+	}
+	return nil
+}
+
+// runCheck выполняет проверку с ограничением по времени и замеряет её длительность
+func runCheck(ctx context.Context, name string, check func(ctx context.Context) error) model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := model.HealthCheck{
+		Name:      name,
+		Status:    model.HealthStatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = model.HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IdempotencyService interface {
+	// Begin сохраняет ключ запроса перед его выполнением и возвращает nil.
+	// Если запрос с этим ключом уже выполнен, возвращается сохранённый ответ
+	Begin(ctx context.Context, clientKey, key, requestHash string) (*model.IdempotencyRecord, error)
+	// Complete сохраняет ответ на выполненный запрос
+	Complete(ctx context.Context, record *model.IdempotencyRecord) error
+	// Release освобождает ключ, если ответ не должен повторяться
+	Release(ctx context.Context, clientKey, key string) error
+}
+
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+	// Время, после которого незавершённый запрос считается прерванным и ключ можно занять снова
+	lockTimeout time.Duration
+	logger      *logrus.Logger
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl, lockTimeout time.Duration, logger *logrus.Logger) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl, lockTimeout: lockTimeout, logger: logger}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, clientKey, key, requestHash string) (*model.IdempotencyRecord, error) {
+	now := time.Now()
+	existing, err := s.repo.Reserve(ctx, &model.IdempotencyRecord{
+		ClientKey:   clientKey,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(s.ttl),
+	}, now.Add(-s.lockTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	entry := logging.FromContext(ctx, s.logger).WithField("idempotency_key", key)
+	if existing.RequestHash != requestHash {
+		entry.Warn("Ключ идемпотентности повторно использован для другого запроса")
+		return nil, apperr.Unprocessable(apperr.CodeIdempotencyKeyReused, "Ключ идемпотентности уже использован для другого запроса")
+	}
+	if !existing.Completed() {
+		return nil, apperr.Conflict(apperr.CodeIdempotencyInProgress, "Запрос с этим ключом идемпотентности ещё выполняется, повторите позже", nil)
+	}
+
+	entry.WithField("status", existing.StatusCode).Info("Повтор запроса по ключу идемпотентности, возвращается сохранённый ответ")
+	return existing, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	return s.repo.Complete(ctx, record)
+}
+
+func (s *idempotencyService) Release(ctx context.Context, clientKey, key string) error {
+	return s.repo.Delete(ctx, clientKey, key)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"testing"
+	"time"
+)
+
+// fakeIdempotencyRepo возвращает из Reserve запись existing и запоминает переданные аргументы
+type fakeIdempotencyRepo struct {
+	repository.IdempotencyRepository
+
+	existing      *model.IdempotencyRecord
+	reserved      *model.IdempotencyRecord
+	lockedBefore  time.Time
+	expiredBefore time.Time
+}
+
+func (r *fakeIdempotencyRepo) Reserve(_ context.Context, record *model.IdempotencyRecord, lockedBefore time.Time) (*model.IdempotencyRecord, error) {
+	r.reserved, r.lockedBefore = record, lockedBefore
+	return r.existing, nil
+}
+
+func (r *fakeIdempotencyRepo) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	r.expiredBefore = before
+	return 0, nil
+}
+
+func TestIdempotencyBegin(t *testing.T) {
+	completed := &model.IdempotencyRecord{RequestHash: "hash", StatusCode: 201, Body: []byte("{}")}
+
+	tests := []struct {
+		name     string
+		existing *model.IdempotencyRecord
+		hash     string
+		want     *model.IdempotencyRecord
+		wantCode string
+	}{
+		{"новый ключ", nil, "hash", nil, ""},
+		{"сохранённый ответ", completed, "hash", completed, ""},
+		{"другой запрос", completed, "other", nil, apperr.CodeIdempotencyKeyReused},
+		{"запрос ещё выполняется", &model.IdempotencyRecord{RequestHash: "hash"}, "hash", nil, apperr.CodeIdempotencyInProgress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeIdempotencyRepo{existing: tt.existing}
+			svc := NewIdempotencyService(repo, 24*time.Hour, time.Minute, newTestLogger())
+
+			start := time.Now()
+			got, err := svc.Begin(context.Background(), "user:1", "key", tt.hash)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("Begin() error = %v, ожидался код %q", err, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("Begin() = %+v, ожидалось %+v", got, tt.want)
+			}
+
+			end := time.Now()
+
+			// Незавершённый запрос старше таймаута блокировки не мешает занять ключ снова
+			if repo.lockedBefore.Before(start.Add(-time.Minute)) || repo.lockedBefore.After(end.Add(-time.Minute)) {
+				t.Errorf("граница блокировки %v, ожидалась минута до вызова", repo.lockedBefore)
+			}
+			if expiresAt := repo.reserved.ExpiresAt; expiresAt.Before(start.Add(24*time.Hour)) || expiresAt.After(end.Add(24*time.Hour)) {
+				t.Errorf("срок хранения ключа до %v, ожидались сутки", expiresAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/logging"
+	"subscription_service/pkg/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Наибольшее количество строк с подписками в файле импорта
+	maxImportRows = 10000
+	// Наибольшая длина строки файла NDJSON в байтах
+	maxImportLineSize = 64 * 1024
+)
+
+// Столбцы файла CSV, совпадают с полями model.CreateSubscriptionRequest
+var (
+	importColumns         = []string{"service_name", "price", "user_id", "start_date", "end_date"}
+	requiredImportColumns = []string{"service_name", "price", "user_id", "start_date"}
+)
+
+// importRecord - строка файла импорта до проверки
+type importRecord struct {
+	// Номер строки в файле, начиная с 1
+	line int
+	req  model.CreateSubscriptionRequest
+	// Ошибка разбора строки, nil - строка разобрана
+	err *apperr.Error
+}
+
+// ImportSubscriptions загружает подписки из файла CSV или NDJSON и возвращает результат по каждой строке.
+// Строки проверяются так же, как при создании подписки, и не должны пересекаться между собой
+// и с существующими периодами подписок. Пользователю с доступом только к своим подпискам
+// разрешено загружать только свои подписки
+func (s *subService) ImportSubscriptions(ctx context.Context, format string, body io.Reader, mode string) (*model.ImportReport, error) {
+	if mode == "" {
+		mode = model.ImportModeAllOrNothing
+	}
+
+	ownerID, err := s.policy.Scope(ctx, ActionCreate, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := decodeImport(format, body)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ImportReport{Mode: mode, Total: len(records), Rows: make([]model.ImportRowResult, len(records))}
+	results := make(map[int]*model.ImportRowResult, len(records))
+	rows := make([]*model.ImportRow, 0, len(records))
+	for i, record := range records {
+		result := &report.Rows[i]
+		result.Line = record.line
+		results[record.line] = result
+
+		sub, err := validateImportRecord(record, ownerID)
+		if err != nil {
+			setImportError(result, model.ImportStatusInvalid, err)
+			continue
+		}
+		rows = append(rows, &model.ImportRow{Line: record.line, Subscription: sub})
+	}
+
+	rows = excludeOverlappingRows(rows, results)
+
+	if len(rows) > 0 && (mode != model.ImportModeAllOrNothing || len(rows) == len(records)) {
+		if err := s.repo.Import(ctx, rows, mode); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, row := range rows {
+		result := results[row.Line]
+		switch row.Status {
+		case model.ImportStatusCreated, model.ImportStatusUpdated:
+			result.Status, result.ID = row.Status, &row.Subscription.ID
+		case model.ImportStatusConflict:
+			setImportError(result, model.ImportStatusConflict,
+				apperr.Conflict(apperr.CodeSubscriptionExists, "Период подписки пересекается с существующим периодом этой подписки", row.Conflict))
+		default:
+			result.Status = model.ImportStatusRejected
+		}
+	}
+
+	for _, result := range report.Rows {
+		switch result.Status {
+		case model.ImportStatusCreated:
+			report.Created++
+		case model.ImportStatusUpdated:
+			report.Updated++
+		case model.ImportStatusInvalid, model.ImportStatusConflict:
+			report.Failed++
+		}
+	}
+	report.Committed = mode != model.ImportModeAllOrNothing || report.Failed == 0
+
+	logging.FromContext(ctx, s.logger).WithFields(logrus.Fields{
+		"mode":      mode,
+		"total":     report.Total,
+		"created":   report.Created,
+		"updated":   report.Updated,
+		"failed":    report.Failed,
+		"committed": report.Committed,
+	}).Info("Импорт подписок завершён")
+
+	return report, nil
+}
+
+// validateImportRecord проверяет строку файла импорта и возвращает подписку для загрузки
+func validateImportRecord(record *importRecord, ownerID *uuid.UUID) (*model.Subscription, error) {
+	rowErr := record.err
+	if rowErr == nil {
+		rowErr = apperr.Validation(apperr.CodeValidationFailed, "Данные подписки не прошли проверку")
+	} else if !rowErr.HasErrors() {
+		return nil, rowErr
+	}
+
+	req := &record.req
+	if req.ServiceName == "" {
+		rowErr.Add("service_name", apperr.FieldRequired, "Обязательное поле")
+	}
+	if !hasFieldError(rowErr, "price") {
+		switch {
+		case req.Price == 0:
+			rowErr.Add("price", apperr.FieldRequired, "Обязательное поле")
+		case req.Price < 1:
+			rowErr.AddParam("price", apperr.FieldTooSmall, "1", "Значение должно быть не меньше 1")
+		}
+	}
+	if !hasFieldError(rowErr, "user_id") && req.UserID == uuid.Nil {
+		rowErr.Add("user_id", apperr.FieldRequired, "Обязательное поле")
+	}
+	if req.StartDate == "" {
+		rowErr.Add("start_date", apperr.FieldRequired, "Обязательное поле")
+	}
+
+	var startDate, endDate *time.Time
+	if req.StartDate != "" {
+		var err error
+		startDate, endDate, err = ParseDate(&req.StartDate, req.EndDate)
+		if dateErr, ok := apperr.As(err); ok {
+			for _, field := range dateErr.Fields {
+				rowErr.AddParam(field.Field, field.Code, field.Param, field.Message)
+			}
+		}
+	}
+
+	if rowErr.HasErrors() {
+		return nil, rowErr
+	}
+
+	if err := validatePeriod("end_date", *startDate, endDate); err != nil {
+		return nil, err
+	}
+
+	if ownerID != nil && req.UserID != *ownerID {
+		return nil, apperr.Forbidden(apperr.CodeAccessDenied, "Нет доступа к подпискам другого пользователя")
+	}
+
+	return &model.Subscription{
+		ServiceName: req.ServiceName,
+		Price:       req.Price,
+		UserID:      req.UserID,
+		StartDate:   *startDate,
+		EndDate:     endDate,
+	}, nil
+}
+
+// excludeOverlappingRows отмечает конфликтом строки, период которых пересекается
+// с периодом той же подписки в одной из предыдущих строк файла, и возвращает остальные
+func excludeOverlappingRows(rows []*model.ImportRow, results map[int]*model.ImportRowResult) []*model.ImportRow {
+	type subscriptionKey struct {
+		userID      uuid.UUID
+		serviceName string
+	}
+
+	accepted := make([]*model.ImportRow, 0, len(rows))
+	periods := make(map[subscriptionKey][]*model.ImportRow)
+	for _, row := range rows {
+		key := subscriptionKey{userID: row.Subscription.UserID, serviceName: row.Subscription.ServiceName}
+
+		var overlapping *model.ImportRow
+		for _, other := range periods[key] {
+			if periodsOverlap(row.Subscription, other.Subscription) {
+				overlapping = other
+				break
+			}
+		}
+		if overlapping != nil {
+			line := strconv.Itoa(overlapping.Line)
+			setImportError(results[row.Line], model.ImportStatusConflict,
+				apperr.Conflict(apperr.CodeSubscriptionExists, "Период подписки пересекается с существующим периодом этой подписки", nil).
+					AddParam("start_date", apperr.FieldOverlapsLine, line, "Период пересекается с периодом той же подписки в строке "+line))
+			continue
+		}
+
+		periods[key] = append(periods[key], row)
+		accepted = append(accepted, row)
+	}
+
+	return accepted
+}
+
+// periodsOverlap сообщает, пересекаются ли периоды подписок, включая границы.
+// Период без даты окончания считается бессрочным
+func periodsOverlap(a, b *model.Subscription) bool {
+	return (b.EndDate == nil || !a.StartDate.After(*b.EndDate)) &&
+		(a.EndDate == nil || !b.StartDate.After(*a.EndDate))
+}
+
+// setImportError записывает в результат строки статус и ошибку
+func setImportError(result *model.ImportRowResult, status string, err error) {
+	result.Status = status
+	if appErr, ok := apperr.As(err); ok {
+		result.Code, result.Message, result.Errors = appErr.Code, appErr.Message, appErr.Fields
+		result.Conflict = appErr.Conflict
+	}
+}
+
+func hasFieldError(err *apperr.Error, field string) bool {
+	return slices.ContainsFunc(err.Fields, func(fieldErr model.FieldError) bool { return fieldErr.Field == field })
+}
+
+// decodeImport разбирает строки файла импорта в формате format
+func decodeImport(format string, body io.Reader) ([]*importRecord, error) {
+	switch format {
+	case model.ImportFormatCSV:
+		return decodeCSV(body)
+	case model.ImportFormatNDJSON:
+		return decodeNDJSON(body)
+	}
+	return nil, apperr.New(apperr.ErrUnsupportedMediaType, apperr.CodeUnsupportedImportFormat, "Ожидается файл в формате text/csv или application/x-ndjson")
+}
+
+// decodeCSV разбирает файл CSV, первая строка которого содержит названия столбцов
+func decodeCSV(body io.Reader) ([]*importRecord, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, invalidImportFile(err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(importColumns, name) {
+			return nil, invalidImportFile(nil).
+				AddParam(name, apperr.FieldNotAllowed, strings.Join(importColumns, ", "), "Допустимые значения: "+strings.Join(importColumns, ", "))
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, invalidImportFile(nil).Add(name, apperr.FieldRequired, "Обязательное поле")
+		}
+	}
+
+	var records []*importRecord
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			records = append(records, &importRecord{line: parseErr.StartLine,
+				err: apperr.Wrap(apperr.ErrValidation, apperr.CodeInvalidRequestBody, "Неверная строка CSV", err)})
+		} else if err != nil {
+			return nil, invalidImportFile(err)
+		} else {
+			line, _ := reader.FieldPos(0)
+			records = append(records, csvRecord(line, fields, columns))
+		}
+
+		if len(records) > maxImportRows {
+			return nil, tooManyImportRows()
+		}
+	}
+
+	return records, nil
+}
+
+// csvRecord преобразует поля строки CSV в запрос создания подписки
+func csvRecord(line int, fields []string, columns map[string]int) *importRecord {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	record := &importRecord{line: line}
+	record.req.ServiceName = value("service_name")
+	record.req.StartDate = value("start_date")
+	if endDate := value("end_date"); endDate != "" {
+		record.req.EndDate = &endDate
+	}
+
+	rowErr := apperr.Validation(apperr.CodeValidationFailed, "Данные подписки не прошли проверку")
+	if price := value("price"); price != "" {
+		parsed, err := strconv.Atoi(price)
+		if err != nil {
+			rowErr.Add("price", apperr.FieldInvalidType, "Неверный тип значения")
+		}
+		record.req.Price = parsed
+	}
+	if userID := value("user_id"); userID != "" {
+		parsed, err := uuid.Parse(userID)
+		if err != nil {
+			rowErr.Add("user_id", apperr.FieldInvalidUUID, "Ожидается UUID")
+		}
+		record.req.UserID = parsed
+	}
+	if rowErr.HasErrors() {
+		record.err = rowErr
+	}
+
+	return record
+}
+
+// decodeNDJSON разбирает файл NDJSON, каждая непустая строка которого - объект CreateSubscriptionRequest
+func decodeNDJSON(body io.Reader) ([]*importRecord, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxImportLineSize)
+
+	var records []*importRecord
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		record := &importRecord{line: line}
+		if err := json.Unmarshal(data, &record.req); err != nil {
+			record.err = jsonRecordError(err)
+		}
+		records = append(records, record)
+
+		if len(records) > maxImportRows {
+			return nil, tooManyImportRows()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, invalidImportFile(err)
+	}
+
+	return records, nil
+}
+
+// jsonRecordError возвращает ошибку поля для значения неверного типа, иначе ошибку всей строки
+func jsonRecordError(err error) *apperr.Error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return apperr.Wrap(apperr.ErrValidation, apperr.CodeValidationFailed, "Данные подписки не прошли проверку", err).
+			Add(typeErr.Field, apperr.FieldInvalidType, "Неверный тип значения")
+	}
+	return apperr.Wrap(apperr.ErrValidation, apperr.CodeInvalidRequestBody, "Неверная строка JSON", err)
+}
+
+// invalidImportFile возвращает ошибку файла импорта, который не удалось разобрать
+func invalidImportFile(err error) *apperr.Error {
+	if err == nil {
+		return apperr.Validation(apperr.CodeInvalidImportFile, "Неверный файл импорта")
+	}
+	return apperr.Wrap(apperr.ErrValidation, apperr.CodeInvalidImportFile, "Неверный файл импорта", err)
+}
+
+func tooManyImportRows() *apperr.Error {
+	limit := strconv.Itoa(maxImportRows)
+	return invalidImportFile(nil).AddParam("rows", apperr.FieldTooLarge, limit, "Значение должно быть не больше "+limit)
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const importUserID = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+func TestDecodeImport(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		body      string
+		wantLines []int
+		wantPrice []int
+		wantRows  []string // коды ошибок строк, "" - строка разобрана
+		wantErr   string   // код ошибки всего файла
+	}{
+		{
+			name:      "CSV с произвольным порядком столбцов",
+			format:    model.ImportFormatCSV,
+			body:      "price,service_name,user_id,start_date,end_date\n400,Yandex Plus," + importUserID + ",07-2025,\n 500 , Okko ," + importUserID + ",08-2025,12-2025\n",
+			wantLines: []int{2, 3},
+			wantPrice: []int{400, 500},
+			wantRows:  []string{"", ""},
+		},
+		{
+			name:      "CSV с неверными значениями",
+			format:    model.ImportFormatCSV,
+			body:      "service_name,price,user_id,start_date\nYandex Plus,abc,user,07-2025\nOkko,\"400,07-2025\n",
+			wantLines: []int{2, 3},
+			wantPrice: []int{0, 0},
+			wantRows:  []string{apperr.CodeValidationFailed, apperr.CodeInvalidRequestBody},
+		},
+		{
+			name:    "CSV без обязательного столбца",
+			format:  model.ImportFormatCSV,
+			body:    "service_name,price,user_id\nYandex Plus,400," + importUserID + "\n",
+			wantErr: apperr.CodeInvalidImportFile,
+		},
+		{
+			name:    "CSV с неизвестным столбцом",
+			format:  model.ImportFormatCSV,
+			body:    "service_name,price,user_id,start_date,comment\n",
+			wantErr: apperr.CodeInvalidImportFile,
+		},
+		{
+			name:    "пустой CSV",
+			format:  model.ImportFormatCSV,
+			wantErr: apperr.CodeInvalidImportFile,
+		},
+		{
+			name:      "NDJSON с пустыми строками",
+			format:    model.ImportFormatNDJSON,
+			body:      `{"service_name":"Yandex Plus","price":400,"user_id":"` + importUserID + `","start_date":"07-2025"}` + "\n\n  \n" + `{"price":"500"}` + "\n{oops\n",
+			wantLines: []int{1, 4, 5},
+			wantPrice: []int{400, 0, 0},
+			wantRows:  []string{"", apperr.CodeValidationFailed, apperr.CodeInvalidRequestBody},
+		},
+		{
+			name:    "слишком длинная строка NDJSON",
+			format:  model.ImportFormatNDJSON,
+			body:    `{"service_name":"` + strings.Repeat("a", maxImportLineSize) + `"}`,
+			wantErr: apperr.CodeInvalidImportFile,
+		},
+		{
+			name:    "слишком много строк",
+			format:  model.ImportFormatNDJSON,
+			body:    strings.Repeat("{}\n", maxImportRows+1),
+			wantErr: apperr.CodeInvalidImportFile,
+		},
+		{
+			name:    "неизвестный формат",
+			format:  "xml",
+			wantErr: apperr.CodeUnsupportedImportFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := decodeImport(tt.format, strings.NewReader(tt.body))
+			if code := errorCode(err); code != tt.wantErr {
+				t.Fatalf("decodeImport() error = %v, ожидался код %q", err, tt.wantErr)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			var lines, prices []int
+			var rows []string
+			for _, record := range records {
+				lines, prices = append(lines, record.line), append(prices, record.req.Price)
+				code := ""
+				if record.err != nil {
+					code = record.err.Code
+				}
+				rows = append(rows, code)
+			}
+			if !slices.Equal(lines, tt.wantLines) || !slices.Equal(prices, tt.wantPrice) || !slices.Equal(rows, tt.wantRows) {
+				t.Errorf("строки %v, цены %v, ошибки %q; ожидались %v, %v, %q", lines, prices, rows, tt.wantLines, tt.wantPrice, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestValidateImportRecord(t *testing.T) {
+	userID := uuid.MustParse(importUserID)
+	valid := model.CreateSubscriptionRequest{ServiceName: "Yandex Plus", Price: 400, UserID: userID, StartDate: "07-2025"}
+	with := func(change func(req *model.CreateSubscriptionRequest)) model.CreateSubscriptionRequest {
+		req := valid
+		change(&req)
+		return req
+	}
+
+	tests := []struct {
+		name       string
+		req        model.CreateSubscriptionRequest
+		ownerID    *uuid.UUID
+		wantCode   string
+		wantFields []string
+	}{
+		{name: "верная строка", req: valid},
+		{name: "своя подписка", req: valid, ownerID: &userID},
+		{name: "пустая строка", req: model.CreateSubscriptionRequest{}, wantCode: apperr.CodeValidationFailed,
+			wantFields: []string{"service_name", "price", "user_id", "start_date"}},
+		{name: "отрицательная цена", req: with(func(req *model.CreateSubscriptionRequest) { req.Price = -1 }),
+			wantCode: apperr.CodeValidationFailed, wantFields: []string{"price"}},
+		{name: "неверные даты", req: with(func(req *model.CreateSubscriptionRequest) { req.StartDate, req.EndDate = "2025-07", ptr("13-2025") }),
+			wantCode: apperr.CodeValidationFailed, wantFields: []string{"start_date", "end_date"}},
+		{name: "окончание раньше начала", req: with(func(req *model.CreateSubscriptionRequest) { req.EndDate = ptr("06-2025") }),
+			wantCode: apperr.CodeInvalidPeriod, wantFields: []string{"end_date"}},
+		{name: "чужая подписка", req: valid, ownerID: ptr(uuid.New()), wantCode: apperr.CodeAccessDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := validateImportRecord(&importRecord{line: 2, req: tt.req}, tt.ownerID)
+			if tt.wantCode == "" {
+				if err != nil || sub == nil {
+					t.Fatalf("validateImportRecord() = %+v, error = %v", sub, err)
+				}
+				return
+			}
+
+			appErr, ok := apperr.As(err)
+			if !ok || appErr.Code != tt.wantCode {
+				t.Fatalf("validateImportRecord() error = %v, ожидался код %q", err, tt.wantCode)
+			}
+			if fields := fieldNames(appErr); !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("ошибки полей %v, ожидались %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestImportSubscriptions(t *testing.T) {
+	userID := uuid.MustParse(importUserID)
+	existing := &model.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 300, UserID: userID,
+		StartDate: month(2025, time.January), EndDate: ptr(month(2025, time.March)), Version: 1}
+
+	row := func(service, price, start, end string) string {
+		return service + "," + price + "," + importUserID + "," + start + "," + end + "\n"
+	}
+	header := "service_name,price,user_id,start_date,end_date\n"
+	valid := header + row("Yandex Plus", "400", "07-2025", "") + row("Okko", "350", "04-2025", "06-2025")
+	withInvalid := valid + row("Kinopoisk", "0", "07-2025", "")
+	overlapsFile := valid + row("Yandex Plus", "500", "09-2025", "")
+	overlapsStored := valid + row("Okko", "500", "03-2025", "")
+	upsert := header + row("Okko", "500", "01-2025", "")
+
+	tests := []struct {
+		name          string
+		mode          string
+		body          string
+		wantCommitted bool
+		wantStatuses  []string
+		wantStored    int
+	}{
+		{"все строки верны", "", valid, true,
+			[]string{model.ImportStatusCreated, model.ImportStatusCreated}, 3},
+		{"all_or_nothing с неверной строкой", model.ImportModeAllOrNothing, withInvalid, false,
+			[]string{model.ImportStatusRejected, model.ImportStatusRejected, model.ImportStatusInvalid}, 1},
+		{"all_or_nothing с пересечением в базе", model.ImportModeAllOrNothing, overlapsStored, false,
+			[]string{model.ImportStatusRejected, model.ImportStatusRejected, model.ImportStatusConflict}, 1},
+		{"skip_invalid с неверной строкой", model.ImportModeSkipInvalid, withInvalid, true,
+			[]string{model.ImportStatusCreated, model.ImportStatusCreated, model.ImportStatusInvalid}, 3},
+		{"skip_invalid с пересечением строк файла", model.ImportModeSkipInvalid, overlapsFile, true,
+			[]string{model.ImportStatusCreated, model.ImportStatusCreated, model.ImportStatusConflict}, 3},
+		{"skip_invalid с пересечением в базе", model.ImportModeSkipInvalid, overlapsStored, true,
+			[]string{model.ImportStatusCreated, model.ImportStatusCreated, model.ImportStatusConflict}, 3},
+		{"upsert обновляет период с той же датой начала", model.ImportModeUpsert, upsert, true,
+			[]string{model.ImportStatusUpdated}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := *existing
+			repo := newFakeRepo(&stored)
+			svc := newTestService(repo)
+
+			report, err := svc.ImportSubscriptions(userContext(userID), model.ImportFormatCSV, strings.NewReader(tt.body), tt.mode)
+			if err != nil {
+				t.Fatalf("ImportSubscriptions() error = %v", err)
+			}
+
+			var statuses []string
+			for _, result := range report.Rows {
+				statuses = append(statuses, result.Status)
+			}
+			if report.Committed != tt.wantCommitted || !slices.Equal(statuses, tt.wantStatuses) {
+				t.Errorf("committed = %v, статусы %v; ожидались %v, %v", report.Committed, statuses, tt.wantCommitted, tt.wantStatuses)
+			}
+			if len(repo.subs) != tt.wantStored {
+				t.Errorf("сохранено %d подписок, ожидалось %d", len(repo.subs), tt.wantStored)
+			}
+			if report.Total != len(tt.wantStatuses) || report.Created+report.Updated+report.Failed > report.Total {
+				t.Errorf("неверные итоги отчёта: %+v", report)
+			}
+		})
+	}
+}
+
+func TestImportSubscriptionsReportsConflicts(t *testing.T) {
+	userID := uuid.MustParse(importUserID)
+	existing := &model.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 300, UserID: userID,
+		StartDate: month(2025, time.January), Version: 1}
+	body := `{"service_name":"Okko","price":400,"user_id":"` + importUserID + `","start_date":"03-2025"}` + "\n" +
+		`{"service_name":"Yandex Plus","price":400,"user_id":"` + importUserID + `","start_date":"07-2025"}` + "\n" +
+		`{"service_name":"Yandex Plus","price":400,"user_id":"` + importUserID + `","start_date":"08-2025"}` + "\n"
+
+	svc := newTestService(newFakeRepo(existing))
+	report, err := svc.ImportSubscriptions(context.Background(), model.ImportFormatNDJSON, strings.NewReader(body), model.ImportModeSkipInvalid)
+	if err != nil {
+		t.Fatalf("ImportSubscriptions() error = %v", err)
+	}
+
+	// Пересечение с сохранённым периодом возвращает этот период
+	if conflict := report.Rows[0].Conflict; report.Rows[0].Code != apperr.CodeSubscriptionExists || conflict == nil || conflict.ID != existing.ID {
+		t.Errorf("строка 1: %+v, ожидался конфликт с сохранённым периодом", report.Rows[0])
+	}
+	// Пересечение со строкой файла указывает номер этой строки
+	if errs := report.Rows[2].Errors; len(errs) != 1 || errs[0].Code != apperr.FieldOverlapsLine || errs[0].Param != "2" {
+		t.Errorf("строка 3: %+v, ожидалось пересечение со строкой 2", report.Rows[2])
+	}
+	if report.Created != 1 || report.Failed != 2 {
+		t.Errorf("создано %d, ошибок %d, ожидалось 1 и 2", report.Created, report.Failed)
+	}
+}
+
+func TestImportSubscriptionsPolicy(t *testing.T) {
+	body := strings.NewReader(`{"service_name":"Okko","price":400,"user_id":"` + importUserID + `","start_date":"03-2025"}`)
+
+	// Пользователю без права создания подписок импорт запрещён целиком
+	policy := DefaultPolicy(newTestLogger())
+	policy.Roles["owner"] = RolePolicy{Scope: ScopeOwn, Actions: []string{ActionRead}}
+	svc := NewSubService(newFakeRepo(), policy, false, newTestLogger())
+	if _, err := svc.ImportSubscriptions(userContext(uuid.New()), model.ImportFormatNDJSON, body, ""); !errors.Is(err, apperr.ErrForbidden) {
+		t.Fatalf("ImportSubscriptions() error = %v, ожидался отказ в доступе", err)
+	}
+
+	// Подписки другого пользователя отклоняются построчно
+	body.Seek(0, io.SeekStart)
+	report, err := newTestService(newFakeRepo()).ImportSubscriptions(userContext(uuid.New()), model.ImportFormatNDJSON, body, model.ImportModeSkipInvalid)
+	if err != nil {
+		t.Fatalf("ImportSubscriptions() error = %v", err)
+	}
+	if report.Rows[0].Status != model.ImportStatusInvalid || report.Rows[0].Code != apperr.CodeAccessDenied {
+		t.Errorf("строка 1: %+v, ожидался отказ в доступе", report.Rows[0])
+	}
+}
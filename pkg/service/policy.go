@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/logging"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Действия с подписками, на которые выдаются разрешения
+const (
+	ActionRead      = "read"
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionDelete    = "delete"
+	ActionAggregate = "aggregate"
+	// ActionAudit - чтение журнала аудита изменений подписок
+	ActionAudit = "audit"
+	// ActionReadDeleted - чтение удалённых подписок с параметром include_deleted
+	ActionReadDeleted = "read_deleted"
+)
+
+// Области действия разрешений роли
+const (
+	// ScopeOwn - только подписки самого пользователя
+	ScopeOwn = "own"
+	// ScopeAll - подписки всех пользователей
+	ScopeAll = "all"
+)
+
+// Действия с подписками без доступа к журналу аудита
+var subscriptionActions = []string{ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionAggregate}
+
+var policyActions = []string{ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionAggregate, ActionAudit, ActionReadDeleted}
+
+// RolePolicy - разрешения роли: действия и подписки, к которым они применимы
+type RolePolicy struct {
+	Scope   string   `yaml:"scope"`
+	Actions []string `yaml:"actions"`
+}
+
+// Policy решает, какие действия с подписками каких пользователей разрешены пользователю запроса.
+// Роли берутся из токена, роль по умолчанию есть у любого аутентифицированного пользователя
+type Policy struct {
+	DefaultRole string                `yaml:"default_role"`
+	Roles       map[string]RolePolicy `yaml:"roles"`
+
+	logger *logrus.Logger
+}
+
+// DefaultPolicy - политика, если файл политики не задан: владелец работает со своими подписками,
+// поддержка читает все подписки, финансы читают и агрегируют все подписки,
+// администратору разрешено всё, включая чтение журнала аудита и удалённых подписок
+func DefaultPolicy(logger *logrus.Logger) *Policy {
+	return &Policy{
+		DefaultRole: "owner",
+		Roles: map[string]RolePolicy{
+			"owner":        {Scope: ScopeOwn, Actions: subscriptionActions},
+			"support":      {Scope: ScopeAll, Actions: []string{ActionRead}},
+			"finance":      {Scope: ScopeAll, Actions: []string{ActionRead, ActionAggregate}},
+			auth.RoleAdmin: {Scope: ScopeAll, Actions: policyActions},
+		},
+		logger: logger,
+	}
+}
+
+// LoadPolicy читает политику из YAML-файла
+func LoadPolicy(path string, logger *logrus.Logger) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{logger: logger}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("parse policy %s: %w", path, err)
+	}
+
+	for name, role := range policy.Roles {
+		if role.Scope != ScopeOwn && role.Scope != ScopeAll {
+			return nil, fmt.Errorf("role %q: unknown scope %q", name, role.Scope)
+		}
+		for _, action := range role.Actions {
+			if !slices.Contains(policyActions, action) {
+				return nil, fmt.Errorf("role %q: unknown action %q", name, action)
+			}
+		}
+	}
+	if _, ok := policy.Roles[policy.DefaultRole]; policy.DefaultRole != "" && !ok {
+		return nil, fmt.Errorf("default role %q is not defined", policy.DefaultRole)
+	}
+
+	return policy, nil
+}
+
+// Authorize проверяет, что пользователю запроса разрешено действие action с подписками пользователя userID.
+// Внутренним сервисам с ключом API разрешены все действия в пределах областей доступа ключа,
+// которые проверяются на уровне маршрутов. Без аутентификации проверка не выполняется
+func (p *Policy) Authorize(ctx context.Context, action string, userID uuid.UUID) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok || principal.IsService() {
+		return nil
+	}
+
+	scope, found := p.scope(principal, action)
+	switch {
+	case scope == ScopeAll:
+		return nil
+	case scope == ScopeOwn && principal.UserID == userID:
+		return nil
+	case found:
+		return p.deny(ctx, principal, action, userID.String(), "действие разрешено только со своими подписками",
+			apperr.CodeAccessDenied, "Нет доступа к подпискам другого пользователя")
+	}
+	return p.deny(ctx, principal, action, userID.String(), "действие не разрешено ни одной ролью пользователя",
+		apperr.CodeActionNotPermitted, "Действие не разрешено ролям пользователя")
+}
+
+// Scope ограничивает фильтр по пользователю для действия над множеством подписок (список, отчёты).
+// Если фильтр не задан, а пользователю разрешены только свои подписки, подставляется его ID
+func (p *Policy) Scope(ctx context.Context, action string, userID *uuid.UUID) (*uuid.UUID, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok || principal.IsService() {
+		return userID, nil
+	}
+
+	if userID == nil {
+		scope, found := p.scope(principal, action)
+		switch {
+		case scope == ScopeAll:
+			return nil, nil
+		case found:
+			return &principal.UserID, nil
+		}
+		return nil, p.deny(ctx, principal, action, "", "действие не разрешено ни одной ролью пользователя",
+			apperr.CodeActionNotPermitted, "Действие не разрешено ролям пользователя")
+	}
+
+	if err := p.Authorize(ctx, action, *userID); err != nil {
+		return nil, err
+	}
+	return userID, nil
+}
+
+// canRead сообщает, разрешено ли пользователю запроса читать подписки пользователя userID, не записывая отказ в лог
+func (p *Policy) canRead(ctx context.Context, userID uuid.UUID) bool {
+	principal, ok := auth.FromContext(ctx)
+	if !ok || principal.IsService() {
+		return true
+	}
+	scope, _ := p.scope(principal, ActionRead)
+	return scope == ScopeAll || (scope == ScopeOwn && principal.UserID == userID)
+}
+
+// scope возвращает самую широкую область действия action среди ролей пользователя
+func (p *Policy) scope(principal *auth.Principal, action string) (string, bool) {
+	roles := principal.Roles
+	if p.DefaultRole != "" {
+		roles = append([]string{p.DefaultRole}, roles...)
+	}
+
+	result, found := "", false
+	for _, name := range roles {
+		role, ok := p.Roles[name]
+		if !ok || !slices.Contains(role.Actions, action) {
+			continue
+		}
+		if role.Scope == ScopeAll {
+			return ScopeAll, true
+		}
+		result, found = role.Scope, true
+	}
+	return result, found
+}
+
+// deny логирует причину отказа и возвращает ошибку с кодом 403 и кодом ошибки code:
+// CodeActionNotPermitted, если действие не разрешено ни одной ролью, или CodeAccessDenied, если подписки чужие
+func (p *Policy) deny(ctx context.Context, principal *auth.Principal, action, targetUserID, reason, code, message string) error {
+	fields := logrus.Fields{
+		"action": action,
+		"roles":  principal.Roles,
+		"reason": reason,
+	}
+	if targetUserID != "" {
+		fields["target_user_id"] = targetUserID
+	}
+	logging.FromContext(ctx, p.logger).WithFields(fields).Warn("Доступ запрещён политикой")
+
+	return apperr.Wrap(apperr.ErrForbidden, code, message, fmt.Errorf("policy: %s: %s", action, reason))
+}
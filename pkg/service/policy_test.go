@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// newTestLogger возвращает логгер, записи которого не выводятся
+func newTestLogger() *logrus.Logger {
+	logger, _ := test.NewNullLogger()
+	return logger
+}
+
+// userContext возвращает контекст запроса пользователя с ролями roles
+func userContext(userID uuid.UUID, roles ...string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{UserID: userID, Roles: roles})
+}
+
+// errorCode возвращает код ошибки приложения или пустую строку
+func errorCode(err error) string {
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	serviceKey := uuid.New()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		action   string
+		userID   uuid.UUID
+		wantCode string
+	}{
+		{"без аутентификации", context.Background(), ActionDelete, other, ""},
+		{"ключ API", auth.WithPrincipal(context.Background(), &auth.Principal{APIKeyID: &serviceKey}), ActionDelete, other, ""},
+		{"своя подписка", userContext(owner), ActionUpdate, owner, ""},
+		{"чужая подписка", userContext(owner), ActionUpdate, other, apperr.CodeAccessDenied},
+		{"поддержка читает чужие подписки", userContext(owner, "support"), ActionRead, other, ""},
+		{"поддержка не удаляет чужие подписки", userContext(owner, "support"), ActionDelete, other, apperr.CodeAccessDenied},
+		{"действие не разрешено ролями", userContext(owner), ActionAudit, owner, apperr.CodeActionNotPermitted},
+		{"администратор", userContext(owner, auth.RoleAdmin), ActionAudit, other, ""},
+	}
+
+	policy := DefaultPolicy(newTestLogger())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(tt.ctx, tt.action, tt.userID)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("Authorize() error = %v, ожидался код %q", err, tt.wantCode)
+			}
+			if err != nil && !errors.Is(err, apperr.ErrForbidden) {
+				t.Errorf("Authorize() error = %v, ожидалась ошибка доступа", err)
+			}
+		})
+	}
+}
+
+func TestPolicyScope(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		action   string
+		userID   *uuid.UUID
+		want     *uuid.UUID
+		wantCode string
+	}{
+		{"свои подписки по умолчанию", userContext(owner), ActionRead, nil, &owner, ""},
+		{"фильтр по себе", userContext(owner), ActionRead, &owner, &owner, ""},
+		{"фильтр по другому пользователю", userContext(owner), ActionRead, &other, nil, apperr.CodeAccessDenied},
+		{"все подписки", userContext(owner, "finance"), ActionAggregate, nil, nil, ""},
+		{"действие не разрешено ролями", userContext(owner), ActionReadDeleted, nil, nil, apperr.CodeActionNotPermitted},
+	}
+
+	policy := DefaultPolicy(newTestLogger())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.Scope(tt.ctx, tt.action, tt.userID)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("Scope() error = %v, ожидался код %q", err, tt.wantCode)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("Scope() = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
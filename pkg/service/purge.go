@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Purger периодически окончательно удаляет подписки, удалённые раньше срока хранения,
+// и ключи идемпотентности с истёкшим сроком хранения
+type Purger struct {
+	repo        repository.Repository
+	idempotency repository.IdempotencyRepository
+	retention   time.Duration
+	interval    time.Duration
+	logger      *logrus.Logger
+}
+
+func NewPurger(repo repository.Repository, idempotency repository.IdempotencyRepository, retention, interval time.Duration, logger *logrus.Logger) *Purger {
+	return &Purger{repo: repo, idempotency: idempotency, retention: retention, interval: interval, logger: logger}
+}
+
+// Run удаляет подписки при запуске и затем каждый interval до отмены ctx
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+		p.purgeIdempotencyKeys(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	deletedBefore := time.Now().Add(-p.retention)
+
+	count, err := p.repo.Purge(ctx, deletedBefore)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.WithError(err).Error("Ошибка окончательного удаления подписок")
+		}
+		return
+	}
+
+	if count > 0 {
+		p.logger.WithFields(logrus.Fields{
+			"count":         count,
+			"deletedBefore": deletedBefore,
+		}).Info("Окончательно удалены подписки с истёкшим сроком хранения")
+	}
+}
+
+func (p *Purger) purgeIdempotencyKeys(ctx context.Context) {
+	count, err := p.idempotency.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.WithError(err).Error("Ошибка удаления просроченных ключей идемпотентности")
+		}
+		return
+	}
+
+	if count > 0 {
+		p.logger.WithField("count", count).Info("Удалены ключи идемпотентности с истёкшим сроком хранения")
+	}
+}
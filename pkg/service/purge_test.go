@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"subscription_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPurger(t *testing.T) {
+	now := time.Now()
+	active := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", UserID: uuid.New(), StartDate: month(2025, time.January), Version: 1}
+	recent := &model.Subscription{ID: uuid.New(), ServiceName: "Kinopoisk", UserID: active.UserID, StartDate: month(2025, time.January),
+		DeletedAt: ptr(now.Add(-24 * time.Hour)), Version: 2}
+	expired := &model.Subscription{ID: uuid.New(), ServiceName: "Okko", UserID: active.UserID, StartDate: month(2024, time.January),
+		DeletedAt: ptr(now.Add(-91 * 24 * time.Hour)), Version: 2}
+
+	repo := newFakeRepo(active, recent, expired)
+	idempotency := &fakeIdempotencyRepo{}
+	purger := NewPurger(repo, idempotency, 90*24*time.Hour, time.Hour, newTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Run выполняет удаление сразу при запуске и завершается после отмены контекста
+	purger.Run(ctx)
+
+	if _, ok := repo.subs[expired.ID]; ok {
+		t.Error("подписка, удалённая раньше срока хранения, не удалена окончательно")
+	}
+	for _, sub := range []*model.Subscription{active, recent} {
+		if _, ok := repo.subs[sub.ID]; !ok {
+			t.Errorf("подписка %s удалена окончательно раньше срока", sub.ServiceName)
+		}
+	}
+	if idempotency.expiredBefore.Before(now) {
+		t.Errorf("ключи идемпотентности удаляются до %v, ожидалось текущее время", idempotency.expiredBefore)
+	}
+}
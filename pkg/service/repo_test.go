@@ -0,0 +1,271 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeRepo - хранилище подписок в памяти для тестов сервиса.
+// Методы, которые тест не использует, не реализованы и вызывают панику
+type fakeRepo struct {
+	repository.Repository
+
+	subs map[uuid.UUID]*model.Subscription
+}
+
+func newFakeRepo(subs ...*model.Subscription) *fakeRepo {
+	r := &fakeRepo{subs: map[uuid.UUID]*model.Subscription{}}
+	for _, sub := range subs {
+		r.subs[sub.ID] = sub
+	}
+	return r
+}
+
+// List повторяет постраничную выборку по ключу репозитория: упорядочивает подписки по полю сортировки и ID
+// и возвращает подписки после курсора. Из фильтров поддерживаются только пользователь и удалённые подписки
+func (r *fakeRepo) List(_ context.Context, filter *model.ListFilter) ([]*model.Subscription, error) {
+	var items []*model.Subscription
+	for _, sub := range r.subs {
+		if (filter.UserID != nil && sub.UserID != *filter.UserID) || (sub.DeletedAt != nil && !filter.IncludeDeleted) {
+			continue
+		}
+		if filter.After != nil && compareListPosition(sub, filter.After, filter) <= 0 {
+			continue
+		}
+		copied := *sub
+		items = append(items, &copied)
+	}
+
+	slices.SortFunc(items, func(a, b *model.Subscription) int {
+		return compareListPosition(a, &model.ListCursor{Value: sortValue(b, filter.SortField), ID: b.ID}, filter)
+	})
+	if len(items) > filter.Limit {
+		items = items[:filter.Limit]
+	}
+	return items, nil
+}
+
+func (r *fakeRepo) Count(_ context.Context, filter *model.ListFilter) (int, error) {
+	all := *filter
+	all.Limit, all.After = len(r.subs), nil
+	items, err := r.List(context.Background(), &all)
+	return len(items), err
+}
+
+// compareListPosition сравнивает место подписки в списке с позицией курсора
+func compareListPosition(sub *model.Subscription, position *model.ListCursor, filter *model.ListFilter) int {
+	var result int
+	switch filter.SortField {
+	case model.SortByPrice:
+		price, _ := strconv.Atoi(position.Value)
+		result = cmp.Compare(sub.Price, price)
+	default:
+		result = strings.Compare(sortValue(sub, filter.SortField), position.Value)
+	}
+	if result == 0 {
+		result = strings.Compare(sub.ID.String(), position.ID.String())
+	}
+	if filter.SortDesc {
+		return -result
+	}
+	return result
+}
+
+func sortValue(sub *model.Subscription, sortField string) string {
+	switch sortField {
+	case model.SortByPrice:
+		return strconv.Itoa(sub.Price)
+	case model.SortByServiceName:
+		return sub.ServiceName
+	}
+	return sub.StartDate.Format(time.DateOnly)
+}
+
+// Import повторяет правила импорта репозитория: строки, пересекающиеся с сохранёнными периодами, получают
+// статус conflict, и в режиме all_or_nothing тогда ничего не сохраняется. В режиме upsert строка с датой начала
+// сохранённого периода обновляет его
+func (r *fakeRepo) Import(ctx context.Context, rows []*model.ImportRow, mode string) error {
+	pending := make([]*model.ImportRow, 0, len(rows))
+	for _, row := range rows {
+		if mode == model.ImportModeUpsert {
+			if existing := r.findByStart(row.Subscription); existing != nil {
+				row.Status = model.ImportStatusUpdated
+				pending = append(pending, row)
+				continue
+			}
+		}
+		conflict, _ := r.FindOverlapping(ctx, &model.SubscriptionPeriod{UserID: row.Subscription.UserID,
+			ServiceName: row.Subscription.ServiceName, StartDate: row.Subscription.StartDate, EndDate: row.Subscription.EndDate})
+		if conflict != nil {
+			row.Status, row.Conflict = model.ImportStatusConflict, conflict
+			continue
+		}
+		row.Status = model.ImportStatusCreated
+		pending = append(pending, row)
+	}
+
+	if len(pending) < len(rows) && mode == model.ImportModeAllOrNothing {
+		for _, row := range pending {
+			row.Status = ""
+		}
+		return nil
+	}
+
+	for _, row := range pending {
+		if row.Status == model.ImportStatusUpdated {
+			existing := r.findByStart(row.Subscription)
+			existing.Price, existing.EndDate = row.Subscription.Price, row.Subscription.EndDate
+			existing.Version++
+			row.Subscription = existing
+			continue
+		}
+		if err := r.Create(ctx, row.Subscription); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findByStart возвращает сохранённый период подписки sub с той же датой начала
+func (r *fakeRepo) findByStart(sub *model.Subscription) *model.Subscription {
+	for _, stored := range r.subs {
+		if stored.UserID == sub.UserID && stored.ServiceName == sub.ServiceName &&
+			stored.StartDate.Equal(sub.StartDate) && stored.DeletedAt == nil {
+			return stored
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id uuid.UUID, includeDeleted bool) (*model.Subscription, error) {
+	sub, ok := r.subs[id]
+	if !ok || (sub.DeletedAt != nil && !includeDeleted) {
+		return nil, apperr.NotFound(apperr.CodeSubscriptionNotFound, "Подписка не найдена")
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+func (r *fakeRepo) Create(_ context.Context, sub *model.Subscription) error {
+	if r.excluded(sub) {
+		return errOverlap
+	}
+	sub.ID, sub.Version = uuid.New(), 1
+	copied := *sub
+	r.subs[sub.ID] = &copied
+	return nil
+}
+
+// errOverlap - ошибка, в которую репозиторий переводит нарушение ограничения исключения
+var errOverlap = apperr.Conflict(apperr.CodeSubscriptionExists, "Период подписки пересекается с существующим периодом этой подписки", nil)
+
+// excluded повторяет ограничение исключения БД: период sub пересекается с другим неудалённым периодом той же подписки
+func (r *fakeRepo) excluded(sub *model.Subscription) bool {
+	period := &model.SubscriptionPeriod{UserID: sub.UserID, ServiceName: sub.ServiceName, StartDate: sub.StartDate, EndDate: sub.EndDate}
+	if sub.ID != uuid.Nil {
+		period.ExcludeID = &sub.ID
+	}
+	conflict, _ := r.FindOverlapping(context.Background(), period)
+	return conflict != nil
+}
+
+// FindOverlapping повторяет условие запроса репозитория: границы периодов включаются, удалённые периоды не учитываются
+func (r *fakeRepo) FindOverlapping(_ context.Context, period *model.SubscriptionPeriod) (*model.Subscription, error) {
+	candidate := &model.Subscription{StartDate: period.StartDate, EndDate: period.EndDate}
+	for _, sub := range r.subs {
+		if sub.UserID != period.UserID || sub.ServiceName != period.ServiceName || sub.DeletedAt != nil {
+			continue
+		}
+		if period.ExcludeID != nil && sub.ID == *period.ExcludeID {
+			continue
+		}
+		if periodsOverlap(sub, candidate) {
+			copied := *sub
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) Update(_ context.Context, sub *model.Subscription) (*model.Subscription, error) {
+	stored, ok := r.subs[sub.ID]
+	if !ok || stored.DeletedAt != nil {
+		return nil, apperr.NotFound(apperr.CodeSubscriptionNotFound, "Подписка не найдена")
+	}
+	if stored.Version != sub.Version {
+		return nil, apperr.PreconditionFailed(apperr.CodeVersionMismatch, "Подписка была изменена")
+	}
+	if r.excluded(sub) {
+		return nil, errOverlap
+	}
+	updated := *sub
+	updated.Version++
+	r.subs[sub.ID] = &updated
+	copied := updated
+	return &copied, nil
+}
+
+func (r *fakeRepo) GetDeleted(_ context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error) {
+	var latest *model.Subscription
+	for _, sub := range r.subs {
+		if sub.UserID != *userID || sub.ServiceName != *serviceName || sub.DeletedAt == nil {
+			continue
+		}
+		if latest == nil || sub.DeletedAt.After(*latest.DeletedAt) {
+			latest = sub
+		}
+	}
+	if latest == nil {
+		return nil, apperr.NotFound(apperr.CodeSubscriptionNotFound, "Подписка не найдена")
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+func (r *fakeRepo) Delete(_ context.Context, id uuid.UUID, version int) error {
+	now := time.Now()
+	_, err := r.changeDeleted(id, version, false, &now)
+	return err
+}
+
+func (r *fakeRepo) Restore(_ context.Context, id uuid.UUID, version int) (*model.Subscription, error) {
+	return r.changeDeleted(id, version, true, nil)
+}
+
+// changeDeleted устанавливает отметку об удалении подписки версии version, если deleted совпадает с её состоянием
+func (r *fakeRepo) changeDeleted(id uuid.UUID, version int, deleted bool, deletedAt *time.Time) (*model.Subscription, error) {
+	sub, ok := r.subs[id]
+	if !ok || (sub.DeletedAt != nil) != deleted {
+		return nil, apperr.NotFound(apperr.CodeSubscriptionNotFound, "Подписка не найдена")
+	}
+	if sub.Version != version {
+		return nil, apperr.PreconditionFailed(apperr.CodeVersionMismatch, "Подписка была изменена")
+	}
+	if deletedAt == nil && r.excluded(sub) {
+		return nil, errOverlap
+	}
+	sub.DeletedAt = deletedAt
+	sub.Version++
+	copied := *sub
+	return &copied, nil
+}
+
+func (r *fakeRepo) Purge(_ context.Context, deletedBefore time.Time) (int64, error) {
+	var count int64
+	for id, sub := range r.subs {
+		if sub.DeletedAt != nil && sub.DeletedAt.Before(deletedBefore) {
+			delete(r.subs, id)
+			count++
+		}
+	}
+	return count, nil
+}
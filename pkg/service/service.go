@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"io"
+	"strings"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/logging"
 	"subscription_service/pkg/model"
 	"subscription_service/pkg/repository"
 	"time"
@@ -12,28 +15,60 @@ import (
 )
 
 type Service interface {
-	CreateSubscription(ctx context.Context, req *model.CreateSubscriptionRequest) error
-	ListSubscriptions(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]*model.Subscription, error)
-	GetSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error)
-	UpdateSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.UpdateSubscriptionRequest) (*model.Subscription, error)
-	DeleteSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string) error
+	CreateSubscription(ctx context.Context, req *model.CreateSubscriptionRequest) (*model.Subscription, error)
+	ListSubscriptions(ctx context.Context, req *model.ListRequest, userID *uuid.UUID) (*model.SubscriptionList, error)
+	GetSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, includeDeleted bool) (*model.Subscription, error)
+	GetSubscriptionByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.Subscription, error)
+	// Методы изменения подписки принимают теги из заголовка If-Match, nil - заголовок не передан
+	UpdateSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.CreateSubscriptionRequest, ifMatch []string) (*model.Subscription, error)
+	UpdateSubscriptionByID(ctx context.Context, id uuid.UUID, req *model.CreateSubscriptionRequest, ifMatch []string) (*model.Subscription, error)
+	PatchSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.PatchSubscriptionRequest, ifMatch []string) (*model.Subscription, error)
+	PatchSubscriptionByID(ctx context.Context, id uuid.UUID, req *model.PatchSubscriptionRequest, ifMatch []string) (*model.Subscription, error)
+	DeleteSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, ifMatch []string) error
+	DeleteSubscriptionByID(ctx context.Context, id uuid.UUID, ifMatch []string) error
+	RestoreSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error)
+	RestoreSubscriptionByID(ctx context.Context, id uuid.UUID) (*model.Subscription, error)
+	// ImportSubscriptions загружает подписки из файла в формате model.ImportFormatCSV или model.ImportFormatNDJSON
+	ImportSubscriptions(ctx context.Context, format string, body io.Reader, mode string) (*model.ImportReport, error)
 	GetTotal(ctx context.Context, req *model.TotalRequest, userID *uuid.UUID) (*model.TotalResponse, error)
+	GetTotalBreakdown(ctx context.Context, req *model.BreakdownRequest, userID *uuid.UUID) ([]*model.BreakdownItem, error)
 }
 
 type subService struct {
 	repo   repository.Repository
-	logger *logrus.Logger
+	policy *Policy
+	// Изменение подписки без заголовка If-Match отклоняется
+	requireIfMatch bool
+	logger         *logrus.Logger
 }
 
-func NewSubService(repo repository.Repository, logger *logrus.Logger) Service {
-	return &subService{repo: repo, logger: logger}
+const (
+	// Формат дат подписок и периодов отчётов
+	dateLayout = "01-2006"
+	// Размер страницы списка подписок по умолчанию
+	defaultListLimit = 50
+	// Допустимые значения параметров sort и group_by для сообщений об ошибках
+	sortFields    = "start_date, price, service_name"
+	groupByFields = "month, service_name, user_id"
+)
+
+func NewSubService(repo repository.Repository, policy *Policy, requireIfMatch bool, logger *logrus.Logger) Service {
+	return &subService{repo: repo, policy: policy, requireIfMatch: requireIfMatch, logger: logger}
 }
 
-func (s *subService) CreateSubscription(ctx context.Context, req *model.CreateSubscriptionRequest) error {
+func (s *subService) CreateSubscription(ctx context.Context, req *model.CreateSubscriptionRequest) (*model.Subscription, error) {
+
+	if err := s.policy.Authorize(ctx, ActionCreate, req.UserID); err != nil {
+		return nil, err
+	}
 
 	startDate, endDate, err := ParseDate(&req.StartDate, req.EndDate)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := validatePeriod("end_date", *startDate, endDate); err != nil {
+		return nil, err
 	}
 
 	subscription := &model.Subscription{
@@ -45,78 +80,542 @@ func (s *subService) CreateSubscription(ctx context.Context, req *model.CreateSu
 	}
 
 	if err := s.repo.Create(ctx, subscription); err != nil {
-		return err
+		return nil, s.describeOverlap(ctx, subscription, err)
 	}
 
-	return nil
+	return subscription, nil
+}
+
+func (s *subService) ListSubscriptions(ctx context.Context, req *model.ListRequest, userID *uuid.UUID) (*model.SubscriptionList, error) {
+
+	userID, err := s.policy.Scope(ctx, ActionRead, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err = s.scopeDeleted(ctx, req.IncludeDeleted, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseListRequest(req, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit = limit + 1
+	items, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = limit
+
+	list := &model.SubscriptionList{Items: items}
+	if len(items) > limit {
+		list.Items = items[:limit]
+		nextCursor, err := encodeCursor(req.Sort, filter.SortField, list.Items[limit-1])
+		if err != nil {
+			return nil, err
+		}
+		list.NextCursor = &nextCursor
+	}
+
+	if req.IncludeTotal {
+		// Общее количество считается без учёта курсора
+		filter.After = nil
+		totalCount, err := s.repo.Count(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		list.TotalCount = &totalCount
+	}
+
+	return list, nil
+}
+
+func (s *subService) GetSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, includeDeleted bool) (*model.Subscription, error) {
+
+	if includeDeleted {
+		if err := s.policy.Authorize(ctx, ActionReadDeleted, *userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.getOwned(ctx, ActionRead, userID, serviceName, includeDeleted)
+}
+
+func (s *subService) GetSubscriptionByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.Subscription, error) {
+
+	sub, err := s.getOwnedByID(ctx, ActionRead, id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	if includeDeleted {
+		if err := s.policy.Authorize(ctx, ActionReadDeleted, sub.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return sub, nil
+}
+
+func (s *subService) UpdateSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.CreateSubscriptionRequest, ifMatch []string) (*model.Subscription, error) {
+
+	sub, err := s.getOwned(ctx, ActionUpdate, userID, serviceName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkIfMatch(sub, ifMatch); err != nil {
+		return nil, err
+	}
+
+	return s.replaceSubscription(ctx, sub, req)
+}
+
+func (s *subService) UpdateSubscriptionByID(ctx context.Context, id uuid.UUID, req *model.CreateSubscriptionRequest, ifMatch []string) (*model.Subscription, error) {
+
+	sub, err := s.getOwnedByID(ctx, ActionUpdate, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkIfMatch(sub, ifMatch); err != nil {
+		return nil, err
+	}
+
+	return s.replaceSubscription(ctx, sub, req)
 }
 
-func (s *subService) ListSubscriptions(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]*model.Subscription, error) {
-	return s.repo.List(ctx, userID, serviceName)
+func (s *subService) PatchSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.PatchSubscriptionRequest, ifMatch []string) (*model.Subscription, error) {
+
+	sub, err := s.getOwned(ctx, ActionUpdate, userID, serviceName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkIfMatch(sub, ifMatch); err != nil {
+		return nil, err
+	}
+
+	return s.patchSubscription(ctx, sub, req)
 }
 
-func (s *subService) GetSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error) {
-	return s.repo.Get(ctx, userID, serviceName)
+func (s *subService) PatchSubscriptionByID(ctx context.Context, id uuid.UUID, req *model.PatchSubscriptionRequest, ifMatch []string) (*model.Subscription, error) {
+
+	sub, err := s.getOwnedByID(ctx, ActionUpdate, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkIfMatch(sub, ifMatch); err != nil {
+		return nil, err
+	}
+
+	return s.patchSubscription(ctx, sub, req)
 }
 
-func (s *subService) UpdateSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.UpdateSubscriptionRequest) (*model.Subscription, error) {
+// replaceSubscription полностью заменяет данные подписки данными запроса
+func (s *subService) replaceSubscription(ctx context.Context, sub *model.Subscription, req *model.CreateSubscriptionRequest) (*model.Subscription, error) {
 
-	startDate, endDate, err := ParseDate(req.StartDate, req.EndDate)
+	// Подписку нельзя передать другому пользователю
+	if err := s.policy.Authorize(ctx, ActionUpdate, req.UserID); err != nil {
+		return nil, err
+	}
+
+	startDate, endDate, err := ParseDate(&req.StartDate, req.EndDate)
 	if err != nil {
 		return nil, err
 	}
 
-	updSub := &model.UpdateSubscription{
-		ServiceName: *serviceName,
-		UserID:      *userID,
+	if err := validatePeriod("end_date", *startDate, endDate); err != nil {
+		return nil, err
+	}
+
+	updated := &model.Subscription{
+		ID:          sub.ID,
+		ServiceName: req.ServiceName,
 		Price:       req.Price,
-		StartDate:   startDate,
+		UserID:      req.UserID,
+		StartDate:   *startDate,
 		EndDate:     endDate,
+		Version:     sub.Version,
 	}
 
-	return s.repo.Update(ctx, updSub)
+	return s.saveSubscription(ctx, updated)
 }
 
-func (s *subService) DeleteSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string) error {
-	return s.repo.Delete(ctx, userID, serviceName)
+// patchSubscription применяет к подписке изменения по правилам JSON Merge Patch
+func (s *subService) patchSubscription(ctx context.Context, sub *model.Subscription, req *model.PatchSubscriptionRequest) (*model.Subscription, error) {
+
+	updated := *sub
+	validationErr := apperr.Validation(apperr.CodeValidationFailed, "Данные подписки не прошли проверку")
+
+	if req.Price.Set {
+		if req.Price.Null || req.Price.Value < 1 {
+			validationErr.AddParam("price", apperr.FieldTooSmall, "1", "Значение должно быть не меньше 1")
+		} else {
+			updated.Price = req.Price.Value
+		}
+	}
+
+	var startDayStr, endDayStr *string
+	if req.StartDate.Set {
+		// Дата начала обязательна и не может быть очищена
+		if req.StartDate.Null {
+			validationErr.Add("start_date", apperr.FieldRequired, "Обязательное поле")
+		} else {
+			startDayStr = &req.StartDate.Value
+		}
+	}
+	if req.EndDate.Set && !req.EndDate.Null {
+		endDayStr = &req.EndDate.Value
+	}
+
+	startDate, endDate, err := ParseDate(startDayStr, endDayStr)
+	if err != nil {
+		parseErr, ok := apperr.As(err)
+		if !ok {
+			return nil, err
+		}
+		validationErr.Fields = append(validationErr.Fields, parseErr.Fields...)
+	}
+
+	if validationErr.HasErrors() {
+		return nil, validationErr
+	}
+
+	if startDate != nil {
+		updated.StartDate = *startDate
+	}
+	if req.EndDate.Set {
+		updated.EndDate = endDate
+	}
+
+	// Переданная дата сверяется с сохранённой датой на другой границе периода,
+	// ошибка относится к полю, которое было изменено
+	field := "end_date"
+	if req.StartDate.Set && !req.EndDate.Set {
+		field = "start_date"
+	}
+	if err := validatePeriod(field, updated.StartDate, updated.EndDate); err != nil {
+		return nil, err
+	}
+
+	return s.saveSubscription(ctx, &updated)
+}
+
+func (s *subService) saveSubscription(ctx context.Context, sub *model.Subscription) (*model.Subscription, error) {
+
+	updated, err := s.repo.Update(ctx, sub)
+	if err != nil {
+		return nil, s.describeOverlap(ctx, sub, err)
+	}
+
+	return updated, nil
+}
+
+func (s *subService) DeleteSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, ifMatch []string) error {
+
+	sub, err := s.getOwned(ctx, ActionDelete, userID, serviceName, false)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkIfMatch(sub, ifMatch); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, sub.ID, sub.Version)
+}
+
+func (s *subService) DeleteSubscriptionByID(ctx context.Context, id uuid.UUID, ifMatch []string) error {
+
+	sub, err := s.getOwnedByID(ctx, ActionDelete, id, false)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkIfMatch(sub, ifMatch); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, sub.ID, sub.Version)
+}
+
+// RestoreSubscription восстанавливает последний удалённый период подписки.
+// Восстановить подписку может тот, кому разрешено её удаление
+func (s *subService) RestoreSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error) {
+
+	if err := s.policy.Authorize(ctx, ActionDelete, *userID); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.GetDeleted(ctx, userID, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.restoreSubscription(ctx, sub)
+}
+
+func (s *subService) RestoreSubscriptionByID(ctx context.Context, id uuid.UUID) (*model.Subscription, error) {
+
+	sub, err := s.getOwnedByID(ctx, ActionDelete, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.DeletedAt == nil {
+		return nil, apperr.Conflict(apperr.CodeSubscriptionNotDeleted, "Подписка не удалена", nil)
+	}
+
+	return s.restoreSubscription(ctx, sub)
+}
+
+// restoreSubscription снимает с подписки отметку об удалении. Если за время удаления
+// был создан пересекающийся с ней период, восстановление отклоняется ограничением БД
+func (s *subService) restoreSubscription(ctx context.Context, sub *model.Subscription) (*model.Subscription, error) {
+	restored, err := s.repo.Restore(ctx, sub.ID, sub.Version)
+	if err != nil {
+		return nil, s.describeOverlap(ctx, sub, err)
+	}
+
+	return restored, nil
 }
 
 func (s *subService) GetTotal(ctx context.Context, req *model.TotalRequest, userID *uuid.UUID) (*model.TotalResponse, error) {
 
-	startPeriod, endPeriod, err := ParseDate(&req.StartPeriod, &req.EndPeriod)
+	userID, err := s.policy.Scope(ctx, ActionAggregate, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err = s.scopeDeleted(ctx, req.IncludeDeleted, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	startPeriod, endPeriod, err := parseReportPeriod(req)
 	if err != nil {
 		return nil, err
 	}
 
 	totalSubscription := &model.TotalSubscription{
-		UserID:      userID,
-		ServiceName: req.ServiceName,
-		StartPeriod: *startPeriod,
-		EndPeriod:   *endPeriod,
+		UserID:         userID,
+		ServiceName:    req.ServiceName,
+		StartPeriod:    *startPeriod,
+		EndPeriod:      *endPeriod,
+		IncludeDeleted: req.IncludeDeleted,
 	}
 
 	return s.repo.GetTotal(ctx, totalSubscription)
 }
 
+func (s *subService) GetTotalBreakdown(ctx context.Context, req *model.BreakdownRequest, userID *uuid.UUID) ([]*model.BreakdownItem, error) {
+
+	userID, err := s.policy.Scope(ctx, ActionAggregate, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err = s.scopeDeleted(ctx, req.IncludeDeleted, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	startPeriod, endPeriod, err := parseReportPeriod(&req.TotalRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &model.BreakdownSubscription{
+		TotalSubscription: model.TotalSubscription{
+			UserID:         userID,
+			ServiceName:    req.ServiceName,
+			StartPeriod:    *startPeriod,
+			EndPeriod:      *endPeriod,
+			IncludeDeleted: req.IncludeDeleted,
+		},
+	}
+
+	// group_by можно передать несколько раз или перечислить через запятую
+	for _, groupBy := range req.GroupBy {
+		for _, field := range strings.Split(groupBy, ",") {
+			switch strings.TrimSpace(field) {
+			case model.GroupByMonth:
+				breakdown.GroupByMonth = true
+			case model.GroupByServiceName:
+				breakdown.GroupByServiceName = true
+			case model.GroupByUserID:
+				breakdown.GroupByUserID = true
+			case "":
+			default:
+				return nil, apperr.Validation(apperr.CodeInvalidQueryParams, "Неверные параметры запроса").
+					AddParam("group_by", apperr.FieldNotAllowed, groupByFields, "Допустимые значения: "+groupByFields)
+			}
+		}
+	}
+
+	// По умолчанию возвращается помесячный временной ряд
+	if !breakdown.GroupByMonth && !breakdown.GroupByServiceName && !breakdown.GroupByUserID {
+		breakdown.GroupByMonth = true
+	}
+
+	return s.repo.GetTotalBreakdown(ctx, breakdown)
+}
+
+// describeOverlap дополняет конфликт пересекающихся периодов, о котором сообщило ограничение БД,
+// найденным пересекающимся периодом. Пересечение отсекается самим ограничением, поиск нужен
+// только для ответа клиенту, поэтому при его неудаче возвращается исходная ошибка
+func (s *subService) describeOverlap(ctx context.Context, sub *model.Subscription, err error) error {
+	appErr, ok := apperr.As(err)
+	if !ok || appErr.Code != apperr.CodeSubscriptionExists {
+		return err
+	}
+
+	period := &model.SubscriptionPeriod{
+		UserID:      sub.UserID,
+		ServiceName: sub.ServiceName,
+		StartDate:   sub.StartDate,
+		EndDate:     sub.EndDate,
+	}
+	if sub.ID != uuid.Nil {
+		period.ExcludeID = &sub.ID
+	}
+
+	conflict, findErr := s.repo.FindOverlapping(ctx, period)
+	if findErr != nil || conflict == nil {
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).WithFields(logrus.Fields{
+		"userId":      sub.UserID,
+		"serviceName": sub.ServiceName,
+		"conflictId":  conflict.ID,
+	}).Warn("Период подписки пересекается с существующим")
+	return apperr.Conflict(apperr.CodeSubscriptionExists, "Период подписки пересекается с существующим периодом этой подписки", conflict)
+}
+
+// parseListRequest проверяет параметры запроса списка подписок и формирует фильтр
+func parseListRequest(req *model.ListRequest, userID *uuid.UUID) (*model.ListFilter, error) {
+	filter := &model.ListFilter{
+		UserID:         userID,
+		ServiceName:    req.ServiceName,
+		ServicePrefix:  req.ServicePrefix,
+		PriceMin:       req.PriceMin,
+		PriceMax:       req.PriceMax,
+		Status:         req.Status,
+		SortField:      model.SortByStartDate,
+		Limit:          req.Limit,
+		IncludeDeleted: req.IncludeDeleted,
+	}
+	validationErr := apperr.Validation(apperr.CodeInvalidQueryParams, "Неверные параметры запроса")
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+
+	// Сортировка по убыванию задаётся префиксом "-", например sort=-price
+	if req.Sort != "" {
+		filter.SortField = strings.TrimPrefix(req.Sort, "-")
+		filter.SortDesc = strings.HasPrefix(req.Sort, "-")
+		switch filter.SortField {
+		case model.SortByStartDate, model.SortByPrice, model.SortByServiceName:
+		default:
+			validationErr.AddParam("sort", apperr.FieldNotAllowed, sortFields, "Допустимые значения: "+sortFields)
+		}
+	}
+
+	if req.ActiveOn != nil {
+		activeOn, err := time.Parse(dateLayout, *req.ActiveOn)
+		if err != nil {
+			validationErr.Add("active_on", apperr.FieldInvalidDate, "Неверный формат даты, ожидается формат MM-YYYY")
+		} else {
+			filter.ActiveOn = &activeOn
+		}
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor, filter.SortField)
+		if err != nil || cursor.Sort != req.Sort {
+			validationErr.Add("cursor", apperr.FieldInvalidCursor, "Курсор повреждён или получен для другой сортировки")
+		} else {
+			filter.After = cursor
+		}
+	}
+
+	if validationErr.HasErrors() {
+		return nil, validationErr
+	}
+
+	if filter.PriceMin != nil && filter.PriceMax != nil && *filter.PriceMin > *filter.PriceMax {
+		return nil, apperr.Unprocessable(apperr.CodeInvalidPriceRange, "Неверный диапазон цен").
+			Add("price_max", apperr.FieldMaxLessThanMin, "Максимальное значение не может быть меньше минимального")
+	}
+
+	return filter, nil
+}
+
+// ParseDate разбирает даты начала и окончания в формате MM-YYYY.
+// При ошибке возвращается *apperr.Error со всеми неверными полями
 func ParseDate(startDayStr *string, endDayStr *string) (*time.Time, *time.Time, error) {
+	return parseDates("start_date", "end_date", startDayStr, endDayStr)
+}
+
+// parseReportPeriod разбирает и проверяет границы периода отчёта
+func parseReportPeriod(req *model.TotalRequest) (*time.Time, *time.Time, error) {
+	startPeriod, endPeriod, err := parseDates("start_period", "end_period", &req.StartPeriod, &req.EndPeriod)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := validatePeriod("end_period", *startPeriod, endPeriod); err != nil {
+		return nil, nil, err
+	}
+
+	return startPeriod, endPeriod, nil
+}
+
+func parseDates(startField, endField string, startDayStr *string, endDayStr *string) (*time.Time, *time.Time, error) {
 	var startDate *time.Time
 	var endDate *time.Time
+	validationErr := apperr.Validation(apperr.CodeInvalidDate, "Неверный формат даты")
 
 	if startDayStr != nil {
-		parsedStartDate, err := time.Parse("01-2006", *startDayStr)
+		parsedStartDate, err := time.Parse(dateLayout, *startDayStr)
 		if err != nil {
-			return startDate, endDate, fmt.Errorf("invalid startDate")
+			validationErr.Add(startField, apperr.FieldInvalidDate, "Неверный формат даты, ожидается формат MM-YYYY")
+		} else {
+			startDate = &parsedStartDate
 		}
-		startDate = &parsedStartDate
 	}
 
 	if endDayStr != nil {
-		parsedEndDate, err := time.Parse("01-2006", *endDayStr)
+		parsedEndDate, err := time.Parse(dateLayout, *endDayStr)
 		if err != nil {
-			return startDate, endDate, fmt.Errorf("invalid endDate")
+			validationErr.Add(endField, apperr.FieldInvalidDate, "Неверный формат даты, ожидается формат MM-YYYY")
+		} else {
+			endDate = &parsedEndDate
 		}
-		endDate = &parsedEndDate
+	}
+
+	if validationErr.HasErrors() {
+		return startDate, endDate, validationErr
 	}
 
 	return startDate, endDate, nil
 }
+
+// validatePeriod проверяет, что дата окончания не раньше даты начала, ошибка относится к полю field
+func validatePeriod(field string, startDate time.Time, endDate *time.Time) error {
+	if endDate != nil && endDate.Before(startDate) {
+		return apperr.Unprocessable(apperr.CodeInvalidPeriod, "Дата окончания не может быть раньше даты начала").
+			Add(field, apperr.FieldEndBeforeStart, "Дата окончания не может быть раньше даты начала")
+	}
+
+	return nil
+}
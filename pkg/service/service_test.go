@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/auth"
+	"subscription_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// month возвращает первое число месяца, в котором начинается или заканчивается подписка
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// fieldNames возвращает имена полей с ошибками в порядке их добавления
+func fieldNames(err *apperr.Error) []string {
+	var fields []string
+	for _, field := range err.Fields {
+		fields = append(fields, field.Field)
+	}
+	return fields
+}
+
+// newTestService создаёт сервис с политикой по умолчанию поверх хранилища в памяти
+func newTestService(repo *fakeRepo) Service {
+	return NewSubService(repo, DefaultPolicy(newTestLogger()), false, newTestLogger())
+}
+
+func TestCreateSubscriptionOverlap(t *testing.T) {
+	userID := uuid.New()
+	existing := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: userID,
+		StartDate: month(2025, time.March), EndDate: ptr(month(2025, time.June)), Version: 1}
+	deleted := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: userID,
+		StartDate: month(2024, time.January), EndDate: ptr(month(2024, time.December)), DeletedAt: ptr(month(2025, time.January)), Version: 2}
+
+	tests := []struct {
+		name        string
+		serviceName string
+		userID      uuid.UUID
+		startDate   string
+		endDate     *string
+		wantErr     bool
+	}{
+		{"период до существующего", "Yandex Plus", userID, "01-2025", ptr("02-2025"), false},
+		{"период после существующего", "Yandex Plus", userID, "07-2025", nil, false},
+		{"общий месяц начала", "Yandex Plus", userID, "01-2025", ptr("03-2025"), true},
+		{"общий месяц окончания", "Yandex Plus", userID, "06-2025", ptr("08-2025"), true},
+		{"вложенный период", "Yandex Plus", userID, "04-2025", ptr("05-2025"), true},
+		{"бессрочный период с более ранним началом", "Yandex Plus", userID, "01-2025", nil, true},
+		{"пересечение с удалённым периодом", "Yandex Plus", userID, "06-2024", ptr("08-2024"), false},
+		{"другой сервис", "Kinopoisk", userID, "04-2025", nil, false},
+		{"другой пользователь", "Yandex Plus", uuid.New(), "04-2025", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeRepo(existing, deleted))
+			created, err := svc.CreateSubscription(context.Background(), &model.CreateSubscriptionRequest{
+				ServiceName: tt.serviceName,
+				Price:       400,
+				UserID:      tt.userID,
+				StartDate:   tt.startDate,
+				EndDate:     tt.endDate,
+			})
+
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("CreateSubscription() error = %v", err)
+				}
+				if created.ID == uuid.Nil {
+					t.Error("подписке не присвоен ID")
+				}
+				return
+			}
+
+			appErr, ok := apperr.As(err)
+			if !ok || !errors.Is(err, apperr.ErrConflict) || appErr.Code != apperr.CodeSubscriptionExists {
+				t.Fatalf("CreateSubscription() error = %v, ожидался конфликт периодов", err)
+			}
+			if appErr.Conflict == nil || appErr.Conflict.ID != existing.ID {
+				t.Errorf("в ошибке конфликта %+v, ожидался существующий период", appErr.Conflict)
+			}
+		})
+	}
+}
+
+// hiddenOverlapRepo не находит пересекающийся период, хотя ограничение БД его отсекает,
+// как если бы конкурентный период ещё не был виден читающему запросу
+type hiddenOverlapRepo struct {
+	*fakeRepo
+}
+
+func (r hiddenOverlapRepo) FindOverlapping(context.Context, *model.SubscriptionPeriod) (*model.Subscription, error) {
+	return nil, nil
+}
+
+func TestCreateSubscriptionOverlapRejectedByConstraint(t *testing.T) {
+	userID := uuid.New()
+	existing := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: userID,
+		StartDate: month(2025, time.March), Version: 1}
+	repo := hiddenOverlapRepo{newFakeRepo(existing)}
+	svc := NewSubService(repo, DefaultPolicy(newTestLogger()), false, newTestLogger())
+
+	_, err := svc.CreateSubscription(context.Background(), &model.CreateSubscriptionRequest{
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		UserID:      userID,
+		StartDate:   "05-2025",
+	})
+
+	appErr, ok := apperr.As(err)
+	if !ok || !errors.Is(err, apperr.ErrConflict) || appErr.Code != apperr.CodeSubscriptionExists {
+		t.Fatalf("CreateSubscription() error = %v, ожидался конфликт периодов", err)
+	}
+	if appErr.Conflict != nil {
+		t.Errorf("в ошибке конфликта %+v, ожидалось отсутствие периода", appErr.Conflict)
+	}
+	if len(repo.subs) != 1 {
+		t.Errorf("сохранено %d периодов, ожидался 1", len(repo.subs))
+	}
+}
+
+func TestUpdateSubscriptionOverlap(t *testing.T) {
+	userID := uuid.New()
+	first := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: userID,
+		StartDate: month(2025, time.January), EndDate: ptr(month(2025, time.March)), Version: 1}
+	second := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: userID,
+		StartDate: month(2025, time.June), Version: 1}
+
+	tests := []struct {
+		name      string
+		startDate string
+		endDate   *string
+		wantErr   bool
+	}{
+		{"период пересекается только с самим собой", "02-2025", ptr("04-2025"), false},
+		{"период доходит до следующего", "01-2025", ptr("06-2025"), true},
+		{"бессрочный период", "01-2025", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeRepo(first, second))
+			_, err := svc.UpdateSubscriptionByID(context.Background(), first.ID, &model.CreateSubscriptionRequest{
+				ServiceName: first.ServiceName,
+				Price:       first.Price,
+				UserID:      userID,
+				StartDate:   tt.startDate,
+				EndDate:     tt.endDate,
+			}, nil)
+
+			if tt.wantErr != (errorCode(err) == apperr.CodeSubscriptionExists) {
+				t.Fatalf("UpdateSubscriptionByID() error = %v, ожидался конфликт: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("UpdateSubscriptionByID() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPatchSubscription(t *testing.T) {
+	userID := uuid.New()
+	original := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: userID,
+		StartDate: month(2025, time.March), EndDate: ptr(month(2025, time.June)), Version: 3}
+
+	set := func(v string) model.Optional[string] { return model.Optional[string]{Set: true, Value: v} }
+	null := model.Optional[string]{Set: true, Null: true}
+
+	tests := []struct {
+		name       string
+		req        model.PatchSubscriptionRequest
+		wantPrice  int
+		wantStart  time.Time
+		wantEnd    *time.Time
+		wantCode   string
+		wantFields []string
+	}{
+		{
+			name:      "пустой документ ничего не меняет",
+			wantPrice: 400, wantStart: month(2025, time.March), wantEnd: ptr(month(2025, time.June)),
+		},
+		{
+			name:      "изменение цены",
+			req:       model.PatchSubscriptionRequest{Price: model.Optional[int]{Set: true, Value: 500}},
+			wantPrice: 500, wantStart: month(2025, time.March), wantEnd: ptr(month(2025, time.June)),
+		},
+		{
+			name:      "null очищает дату окончания",
+			req:       model.PatchSubscriptionRequest{EndDate: null},
+			wantPrice: 400, wantStart: month(2025, time.March),
+		},
+		{
+			name:      "перенос даты начала",
+			req:       model.PatchSubscriptionRequest{StartDate: set("01-2025")},
+			wantPrice: 400, wantStart: month(2025, time.January), wantEnd: ptr(month(2025, time.June)),
+		},
+		{
+			name:       "null в цене",
+			req:        model.PatchSubscriptionRequest{Price: model.Optional[int]{Set: true, Null: true}},
+			wantCode:   apperr.CodeValidationFailed,
+			wantFields: []string{"price"},
+		},
+		{
+			name:       "null в дате начала",
+			req:        model.PatchSubscriptionRequest{StartDate: null},
+			wantCode:   apperr.CodeValidationFailed,
+			wantFields: []string{"start_date"},
+		},
+		{
+			name:       "все ошибки полей сразу",
+			req:        model.PatchSubscriptionRequest{Price: model.Optional[int]{Set: true, Value: 0}, EndDate: set("2025-12")},
+			wantCode:   apperr.CodeValidationFailed,
+			wantFields: []string{"price", "end_date"},
+		},
+		{
+			name:       "дата начала позже сохранённой даты окончания",
+			req:        model.PatchSubscriptionRequest{StartDate: set("07-2025")},
+			wantCode:   apperr.CodeInvalidPeriod,
+			wantFields: []string{"start_date"},
+		},
+		{
+			name:       "дата окончания раньше сохранённой даты начала",
+			req:        model.PatchSubscriptionRequest{EndDate: set("01-2025")},
+			wantCode:   apperr.CodeInvalidPeriod,
+			wantFields: []string{"end_date"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeRepo(original))
+			got, err := svc.PatchSubscriptionByID(context.Background(), original.ID, &tt.req, nil)
+
+			if tt.wantCode != "" {
+				appErr, ok := apperr.As(err)
+				if !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("PatchSubscriptionByID() error = %v, ожидался код %q", err, tt.wantCode)
+				}
+				if fields := fieldNames(appErr); !slices.Equal(fields, tt.wantFields) {
+					t.Errorf("ошибки полей %v, ожидались %v", fields, tt.wantFields)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("PatchSubscriptionByID() error = %v", err)
+			}
+			if got.Price != tt.wantPrice || !got.StartDate.Equal(tt.wantStart) {
+				t.Errorf("подписка %+v, ожидались цена %d и начало %v", got, tt.wantPrice, tt.wantStart)
+			}
+			if (got.EndDate == nil) != (tt.wantEnd == nil) || (got.EndDate != nil && !got.EndDate.Equal(*tt.wantEnd)) {
+				t.Errorf("дата окончания %v, ожидалась %v", got.EndDate, tt.wantEnd)
+			}
+			if got.ServiceName != original.ServiceName || got.UserID != original.UserID || got.Version != original.Version+1 {
+				t.Errorf("изменены поля, которых нет в документе: %+v", got)
+			}
+		})
+	}
+}
+
+func TestListSubscriptionsPages(t *testing.T) {
+	userID := uuid.New()
+	// Одинаковые цены и даты проверяют, что подписки с равным значением сортировки не теряются между страницами
+	var subs []*model.Subscription
+	for i := range 7 {
+		subs = append(subs, &model.Subscription{
+			ID:          uuid.New(),
+			ServiceName: "Service " + strconv.Itoa(i),
+			Price:       100 * (1 + i%3),
+			UserID:      userID,
+			StartDate:   month(2025, time.Month(1+i%2)),
+			Version:     1,
+		})
+	}
+	subs = append(subs, &model.Subscription{ID: uuid.New(), ServiceName: "Other", Price: 100, UserID: uuid.New(), StartDate: month(2025, time.January), Version: 1})
+
+	for _, sort := range []string{"", "-start_date", "price", "-price", "service_name"} {
+		t.Run("sort="+sort, func(t *testing.T) {
+			svc := newTestService(newFakeRepo(subs...))
+			req := &model.ListRequest{Sort: sort, Limit: 3, IncludeTotal: true}
+
+			seen := map[uuid.UUID]bool{}
+			for page := 1; ; page++ {
+				list, err := svc.ListSubscriptions(context.Background(), req, &userID)
+				if err != nil {
+					t.Fatalf("страница %d: ListSubscriptions() error = %v", page, err)
+				}
+				if list.TotalCount == nil || *list.TotalCount != 7 {
+					t.Errorf("страница %d: total_count = %v, ожидалось 7", page, list.TotalCount)
+				}
+				for _, item := range list.Items {
+					if seen[item.ID] {
+						t.Errorf("подписка %s повторяется на странице %d", item.ServiceName, page)
+					}
+					seen[item.ID] = true
+				}
+				if list.NextCursor == nil {
+					break
+				}
+				if len(list.Items) != req.Limit {
+					t.Errorf("страница %d: %d подписок, ожидалось %d", page, len(list.Items), req.Limit)
+				}
+				req.Cursor = *list.NextCursor
+			}
+
+			if len(seen) != 7 {
+				t.Errorf("получено %d подписок пользователя, ожидалось 7", len(seen))
+			}
+		})
+	}
+}
+
+func TestParseListRequest(t *testing.T) {
+	cursor, err := encodeCursor("price", model.SortByPrice, &model.Subscription{ID: uuid.New(), Price: 400})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		req        model.ListRequest
+		wantSort   string
+		wantDesc   bool
+		wantLimit  int
+		wantCode   string
+		wantFields []string
+	}{
+		{name: "значения по умолчанию", wantSort: model.SortByStartDate, wantLimit: defaultListLimit},
+		{name: "сортировка по убыванию", req: model.ListRequest{Sort: "-price", Limit: 10}, wantSort: model.SortByPrice, wantDesc: true, wantLimit: 10},
+		{name: "курсор той же сортировки", req: model.ListRequest{Sort: "price", Cursor: cursor}, wantSort: model.SortByPrice, wantLimit: defaultListLimit},
+		{name: "неизвестное поле сортировки", req: model.ListRequest{Sort: "user_id"}, wantCode: apperr.CodeInvalidQueryParams, wantFields: []string{"sort"}},
+		{name: "курсор другой сортировки", req: model.ListRequest{Sort: "-price", Cursor: cursor}, wantCode: apperr.CodeInvalidQueryParams, wantFields: []string{"cursor"}},
+		{name: "повреждённый курсор", req: model.ListRequest{Cursor: "abc"}, wantCode: apperr.CodeInvalidQueryParams, wantFields: []string{"cursor"}},
+		{name: "неверная дата и сортировка", req: model.ListRequest{Sort: "id", ActiveOn: ptr("2025-07")}, wantCode: apperr.CodeInvalidQueryParams, wantFields: []string{"sort", "active_on"}},
+		{name: "минимальная цена больше максимальной", req: model.ListRequest{PriceMin: ptr(500), PriceMax: ptr(100)}, wantCode: apperr.CodeInvalidPriceRange, wantFields: []string{"price_max"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseListRequest(&tt.req, nil)
+
+			if tt.wantCode != "" {
+				appErr, ok := apperr.As(err)
+				if !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("parseListRequest() error = %v, ожидался код %q", err, tt.wantCode)
+				}
+				if fields := fieldNames(appErr); !slices.Equal(fields, tt.wantFields) {
+					t.Errorf("ошибки полей %v, ожидались %v", fields, tt.wantFields)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseListRequest() error = %v", err)
+			}
+			if filter.SortField != tt.wantSort || filter.SortDesc != tt.wantDesc || filter.Limit != tt.wantLimit {
+				t.Errorf("фильтр %+v, ожидались сортировка %q (по убыванию: %v) и лимит %d", filter, tt.wantSort, tt.wantDesc, tt.wantLimit)
+			}
+			if (tt.req.Cursor != "") != (filter.After != nil) {
+				t.Errorf("курсор в фильтре %+v", filter.After)
+			}
+		})
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	owner := uuid.New()
+	sub := &model.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: owner,
+		StartDate: month(2025, time.January), Version: 1}
+	repo := newFakeRepo(sub)
+	svc := newTestService(repo)
+	ctx := userContext(owner)
+
+	if _, err := svc.RestoreSubscriptionByID(ctx, sub.ID); errorCode(err) != apperr.CodeSubscriptionNotDeleted {
+		t.Fatalf("восстановление неудалённой подписки: error = %v, ожидался код %q", err, apperr.CodeSubscriptionNotDeleted)
+	}
+
+	if err := svc.DeleteSubscriptionByID(ctx, sub.ID, nil); err != nil {
+		t.Fatalf("DeleteSubscriptionByID() error = %v", err)
+	}
+	if _, ok := repo.subs[sub.ID]; !ok {
+		t.Fatal("подписка удалена окончательно, ожидалась отметка об удалении")
+	}
+
+	if _, err := svc.GetSubscriptionByID(ctx, sub.ID, false); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("удалённая подписка без include_deleted: error = %v, ожидалось «не найдено»", err)
+	}
+	if _, err := svc.GetSubscriptionByID(ctx, sub.ID, true); errorCode(err) != apperr.CodeActionNotPermitted {
+		t.Errorf("include_deleted без разрешения: error = %v, ожидался код %q", err, apperr.CodeActionNotPermitted)
+	}
+	deleted, err := svc.GetSubscriptionByID(userContext(uuid.New(), auth.RoleAdmin), sub.ID, true)
+	if err != nil || deleted.DeletedAt == nil {
+		t.Fatalf("include_deleted администратором: %+v, error = %v", deleted, err)
+	}
+
+	// Пока подписка удалена, на тот же период можно оформить новую, и восстановить старую уже нельзя
+	created, err := svc.CreateSubscription(ctx, &model.CreateSubscriptionRequest{ServiceName: sub.ServiceName, Price: 500, UserID: owner, StartDate: "03-2025"})
+	if err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+	if _, err := svc.RestoreSubscription(ctx, &owner, &sub.ServiceName); errorCode(err) != apperr.CodeSubscriptionExists {
+		t.Fatalf("восстановление пересекающегося периода: error = %v, ожидался код %q", err, apperr.CodeSubscriptionExists)
+	}
+
+	if err := svc.DeleteSubscriptionByID(ctx, created.ID, nil); err != nil {
+		t.Fatalf("DeleteSubscriptionByID() error = %v", err)
+	}
+	restored, err := svc.RestoreSubscriptionByID(ctx, sub.ID)
+	if err != nil {
+		t.Fatalf("RestoreSubscriptionByID() error = %v", err)
+	}
+	if restored.DeletedAt != nil || restored.Version != 3 {
+		t.Errorf("восстановленная подписка %+v, ожидались снятая отметка и версия 3", restored)
+	}
+}
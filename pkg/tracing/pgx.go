@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// Строковые и числовые литералы в тексте запроса заменяются на ?, комментарии удаляются,
+	// параметры запроса ($1, $2, ...) остаются без изменений. Строки и комментарии ищутся
+	// одним выражением, чтобы кавычка в комментарии и -- в строке не сбивали разбор
+	stringOrComment = regexp.MustCompile(`'(?:[^']|'')*'|--[^\n]*|/\*[\s\S]*?\*/`)
+	numericLiteral  = regexp.MustCompile(`([^$\w.])\d+(?:\.\d+)?\b`)
+	whitespace      = regexp.MustCompile(`\s+`)
+)
+
+// QueryTracer создаёт спаны запросов pgx с очищенным от литералов текстом запроса
+// и количеством затронутых строк. Значения параметров запроса в спаны не попадают
+type QueryTracer struct{}
+
+var (
+	_ pgx.QueryTracer    = QueryTracer{}
+	_ pgx.CopyFromTracer = QueryTracer{}
+)
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	query := SanitizeSQL(data.SQL)
+	operation := operationName(query)
+
+	ctx, _ = tracer().Start(ctx, "db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		),
+	)
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+func (QueryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	ctx, _ = tracer().Start(ctx, "db COPY",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName("COPY"),
+			semconv.DBCollectionName(data.TableName.Sanitize()),
+		),
+	)
+	return ctx
+}
+
+func (QueryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endSpan(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// endSpan записывает в спан количество строк и ошибку запроса
+func endSpan(ctx context.Context, rows int64, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.response.rows", rows))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// SanitizeSQL убирает из текста запроса литералы, комментарии и лишние пробелы
+func SanitizeSQL(query string) string {
+	query = stringOrComment.ReplaceAllStringFunc(query, func(token string) string {
+		if strings.HasPrefix(token, "'") {
+			return "?"
+		}
+		return " "
+	})
+	query = numericLiteral.ReplaceAllString(query, "${1}?")
+	return strings.TrimSpace(whitespace.ReplaceAllString(query, " "))
+}
+
+// operationName возвращает первое ключевое слово запроса (SELECT, INSERT, WITH, ...)
+func operationName(query string) string {
+	operation, _, _ := strings.Cut(query, " ")
+	return strings.ToUpper(operation)
+}
@@ -0,0 +1,33 @@
+package tracing
+
+import "testing"
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"строковый литерал", "SELECT id FROM subscriptions WHERE service_name = 'Yandex Plus'", "SELECT id FROM subscriptions WHERE service_name = ?"},
+		{"экранированная кавычка", "SELECT 'it''s' AS name", "SELECT ? AS name"},
+		{"пустая строка", "SELECT '' AS name", "SELECT ? AS name"},
+		{"целое число", "SELECT id FROM subscriptions WHERE price > 400 LIMIT 10", "SELECT id FROM subscriptions WHERE price > ? LIMIT ?"},
+		{"дробное число", "SELECT price * 1.5 FROM subscriptions", "SELECT price * ? FROM subscriptions"},
+		{"параметры запроса", "SELECT id FROM subscriptions WHERE user_id = $1 AND price > $12", "SELECT id FROM subscriptions WHERE user_id = $1 AND price > $12"},
+		{"цифры в идентификаторах", "SELECT t1.col2 FROM table3 t1", "SELECT t1.col2 FROM table3 t1"},
+		{"приведение типа параметра", "SELECT $2::date, interval '1 month'", "SELECT $2::date, interval ?"},
+		{"строчный комментарий", "SELECT id -- выборка 'секретного' значения 42\nFROM subscriptions", "SELECT id FROM subscriptions"},
+		{"блочный комментарий", "SELECT /* price = 400 */ id FROM subscriptions", "SELECT id FROM subscriptions"},
+		{"многострочный блочный комментарий", "/* первая строка\nвторая строка */ SELECT 1", "SELECT ?"},
+		{"комментарий внутри строки", "SELECT '-- не комментарий' AS note, 7", "SELECT ? AS note, ?"},
+		{"лишние пробелы", "  SELECT id\n\t FROM   subscriptions  ", "SELECT id FROM subscriptions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeSQL(tt.query); got != tt.want {
+				t.Errorf("SanitizeSQL(%q) = %q, ожидалось %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tracedRepo создаёт спан на каждый вызов репозитория подписок,
+// спаны отдельных SQL-запросов создаёт QueryTracer
+type tracedRepo struct {
+	repo repository.Repository
+}
+
+// Repository оборачивает репозиторий подписок трассировкой
+func Repository(repo repository.Repository) repository.Repository {
+	return &tracedRepo{repo: repo}
+}
+
+func (t *tracedRepo) List(ctx context.Context, filter *model.ListFilter) ([]*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "repository.List")
+	result, err := t.repo.List(ctx, filter)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) Count(ctx context.Context, filter *model.ListFilter) (int, error) {
+	ctx, span := startSpan(ctx, "repository.Count")
+	result, err := t.repo.Count(ctx, filter)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) Create(ctx context.Context, sub *model.Subscription) error {
+	ctx, span := startSpan(ctx, "repository.Create")
+	err := t.repo.Create(ctx, sub)
+	endSpanWithError(span, err)
+	return err
+}
+
+func (t *tracedRepo) Get(ctx context.Context, userID *uuid.UUID, serviceName *string, includeDeleted bool) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "repository.Get")
+	result, err := t.repo.Get(ctx, userID, serviceName, includeDeleted)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "repository.GetByID")
+	result, err := t.repo.GetByID(ctx, id, includeDeleted)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) GetDeleted(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "repository.GetDeleted")
+	result, err := t.repo.GetDeleted(ctx, userID, serviceName)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) FindOverlapping(ctx context.Context, period *model.SubscriptionPeriod) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "repository.FindOverlapping")
+	result, err := t.repo.FindOverlapping(ctx, period)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) Update(ctx context.Context, sub *model.Subscription) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "repository.Update")
+	result, err := t.repo.Update(ctx, sub)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	ctx, span := startSpan(ctx, "repository.Delete")
+	err := t.repo.Delete(ctx, id, version)
+	endSpanWithError(span, err)
+	return err
+}
+
+func (t *tracedRepo) Restore(ctx context.Context, id uuid.UUID, version int) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "repository.Restore")
+	result, err := t.repo.Restore(ctx, id, version)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "repository.Purge")
+	result, err := t.repo.Purge(ctx, deletedBefore)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) Import(ctx context.Context, rows []*model.ImportRow, mode string) error {
+	ctx, span := startSpan(ctx, "repository.Import")
+	err := t.repo.Import(ctx, rows, mode)
+	endSpanWithError(span, err)
+	return err
+}
+
+func (t *tracedRepo) GetTotal(ctx context.Context, req *model.TotalSubscription) (*model.TotalResponse, error) {
+	ctx, span := startSpan(ctx, "repository.GetTotal")
+	result, err := t.repo.GetTotal(ctx, req)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) GetTotalBreakdown(ctx context.Context, req *model.BreakdownSubscription) ([]*model.BreakdownItem, error) {
+	ctx, span := startSpan(ctx, "repository.GetTotalBreakdown")
+	result, err := t.repo.GetTotalBreakdown(ctx, req)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedRepo) ServiceStats(ctx context.Context) ([]*model.ServiceStats, error) {
+	ctx, span := startSpan(ctx, "repository.ServiceStats")
+	result, err := t.repo.ServiceStats(ctx)
+	endSpanWithError(span, err)
+	return result, err
+}
@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"subscription_service/pkg/model"
+	"subscription_service/pkg/service"
+
+	"github.com/google/uuid"
+)
+
+// tracedService создаёт спан на каждый вызов сервиса подписок
+type tracedService struct {
+	service service.Service
+}
+
+// Service оборачивает сервис подписок трассировкой
+func Service(svc service.Service) service.Service {
+	return &tracedService{service: svc}
+}
+
+func (t *tracedService) CreateSubscription(ctx context.Context, req *model.CreateSubscriptionRequest) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.CreateSubscription")
+	result, err := t.service.CreateSubscription(ctx, req)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) ListSubscriptions(ctx context.Context, req *model.ListRequest, userID *uuid.UUID) (*model.SubscriptionList, error) {
+	ctx, span := startSpan(ctx, "service.ListSubscriptions")
+	result, err := t.service.ListSubscriptions(ctx, req, userID)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) GetSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, includeDeleted bool) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.GetSubscription")
+	result, err := t.service.GetSubscription(ctx, userID, serviceName, includeDeleted)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) GetSubscriptionByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.GetSubscriptionByID")
+	result, err := t.service.GetSubscriptionByID(ctx, id, includeDeleted)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) UpdateSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.CreateSubscriptionRequest, ifMatch []string) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.UpdateSubscription")
+	result, err := t.service.UpdateSubscription(ctx, userID, serviceName, req, ifMatch)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) UpdateSubscriptionByID(ctx context.Context, id uuid.UUID, req *model.CreateSubscriptionRequest, ifMatch []string) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.UpdateSubscriptionByID")
+	result, err := t.service.UpdateSubscriptionByID(ctx, id, req, ifMatch)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) PatchSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, req *model.PatchSubscriptionRequest, ifMatch []string) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.PatchSubscription")
+	result, err := t.service.PatchSubscription(ctx, userID, serviceName, req, ifMatch)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) PatchSubscriptionByID(ctx context.Context, id uuid.UUID, req *model.PatchSubscriptionRequest, ifMatch []string) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.PatchSubscriptionByID")
+	result, err := t.service.PatchSubscriptionByID(ctx, id, req, ifMatch)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) DeleteSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string, ifMatch []string) error {
+	ctx, span := startSpan(ctx, "service.DeleteSubscription")
+	err := t.service.DeleteSubscription(ctx, userID, serviceName, ifMatch)
+	endSpanWithError(span, err)
+	return err
+}
+
+func (t *tracedService) DeleteSubscriptionByID(ctx context.Context, id uuid.UUID, ifMatch []string) error {
+	ctx, span := startSpan(ctx, "service.DeleteSubscriptionByID")
+	err := t.service.DeleteSubscriptionByID(ctx, id, ifMatch)
+	endSpanWithError(span, err)
+	return err
+}
+
+func (t *tracedService) RestoreSubscription(ctx context.Context, userID *uuid.UUID, serviceName *string) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.RestoreSubscription")
+	result, err := t.service.RestoreSubscription(ctx, userID, serviceName)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) RestoreSubscriptionByID(ctx context.Context, id uuid.UUID) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "service.RestoreSubscriptionByID")
+	result, err := t.service.RestoreSubscriptionByID(ctx, id)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) ImportSubscriptions(ctx context.Context, format string, body io.Reader, mode string) (*model.ImportReport, error) {
+	ctx, span := startSpan(ctx, "service.ImportSubscriptions")
+	result, err := t.service.ImportSubscriptions(ctx, format, body, mode)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) GetTotal(ctx context.Context, req *model.TotalRequest, userID *uuid.UUID) (*model.TotalResponse, error) {
+	ctx, span := startSpan(ctx, "service.GetTotal")
+	result, err := t.service.GetTotal(ctx, req, userID)
+	endSpanWithError(span, err)
+	return result, err
+}
+
+func (t *tracedService) GetTotalBreakdown(ctx context.Context, req *model.BreakdownRequest, userID *uuid.UUID) ([]*model.BreakdownItem, error) {
+	ctx, span := startSpan(ctx, "service.GetTotalBreakdown")
+	result, err := t.service.GetTotalBreakdown(ctx, req, userID)
+	endSpanWithError(span, err)
+	return result, err
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"subscription_service/pkg/apperr"
+	"subscription_service/pkg/config"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Имя инструментирующей библиотеки для спанов сервиса
+const instrumentationName = "subscription_service"
+
+// tracer создаёт спаны слоёв сервиса и репозитория через глобальный TracerProvider
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Init настраивает глобальный TracerProvider и распространение контекста в формате W3C traceparent.
+// Возвращаемая функция отправляет накопленные спаны и останавливает экспортёр
+func Init(ctx context.Context, cfg config.TracingConfig, logger *logrus.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.WithError(err).Warn("Ошибка трассировки")
+	}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case config.TracingExporterNone, "":
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		logger.Info("Трассировка отключена")
+		return func(context.Context) error { return nil }, nil
+	case config.TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case config.TracingExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	logger.WithField("exporter", cfg.Exporter).Info("Трассировка включена")
+	return provider.Shutdown, nil
+}
+
+// startSpan начинает спан вызова метода сервиса или репозитория
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name)
+}
+
+// endSpanWithError завершает спан. Ошибки apperr (неверный запрос, подписка не найдена и т.п.)
+// записываются кодом ошибки, остальные отмечают спан как ошибочный
+func endSpanWithError(span trace.Span, err error) {
+	defer span.End()
+	if err == nil {
+		return
+	}
+
+	if appErr, ok := apperr.As(err); ok {
+		span.SetAttributes(attribute.String("error.code", appErr.Code))
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}